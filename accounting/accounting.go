@@ -0,0 +1,79 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+/*
+Package accounting records who ran which operational mode commands.
+An Accounter is handed a Record for each command once it has finished,
+whichever of the YANG or template command trees it was resolved from.
+Paths are always presented with secret values masked.
+*/
+package accounting
+
+import (
+	"context"
+	"time"
+
+	"github.com/danos/op/auth"
+	"github.com/danos/op/tmpl"
+	"github.com/danos/op/tmpl/tree"
+)
+
+// Record describes a single run of a command. Path is the command path
+// with secret elements shown as ****.
+type Record struct {
+	Path       string
+	User       auth.User
+	Start      time.Time
+	Stop       time.Time
+	ExitStatus int
+	Privileged bool
+	Local      bool
+}
+
+// NewRecord returns a record of user running the resolved command inv
+func NewRecord(user auth.User, inv *tmpl.Invocation) *Record {
+	rec := &Record{
+		Path: tree.Path(inv.Path).StringByAttrs(inv.PathAttrs()),
+		User: user,
+	}
+	if inv.Template != nil {
+		rec.Privileged = inv.Template.Priv()
+		rec.Local = inv.Template.Local()
+	}
+	return rec
+}
+
+// Accounter is the hook invoked for each command run
+type Accounter interface {
+	Account(ctx context.Context, rec *Record) error
+}
+
+// Func adapts a function to the Accounter interface
+type Func func(ctx context.Context, rec *Record) error
+
+func (f Func) Account(ctx context.Context, rec *Record) error {
+	return f(ctx, rec)
+}
+
+// Run calls fn, recording its start and stop times and the exit status
+// it returns in rec, then passes rec to a. A nil Accounter records
+// nothing. The error from fn takes precedence over that from a.
+func Run(
+	ctx context.Context,
+	a Accounter,
+	rec *Record,
+	fn func() (int, error),
+) error {
+	rec.Start = time.Now()
+	status, err := fn()
+	rec.Stop = time.Now()
+	rec.ExitStatus = status
+	if a == nil {
+		return err
+	}
+	if aerr := a.Account(ctx, rec); err == nil {
+		err = aerr
+	}
+	return err
+}
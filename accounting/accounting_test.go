@@ -0,0 +1,227 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package accounting
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/danos/op/auth"
+	"github.com/danos/op/tmpl"
+)
+
+func testInvocation() *tmpl.Invocation {
+	t := tmpl.NewOpTmpl("", "", "", "set-password $3 $5")
+	t.SetPriv(true)
+	return &tmpl.Invocation{
+		Path:     []string{"set", "user", "fred", "password", "s3cret"},
+		Template: t,
+		Bindings: []tmpl.Binding{
+			{Name: "user", Value: "fred", Index: 2},
+			{Name: "password", Value: "s3cret", Secret: true, Index: 4},
+		},
+	}
+}
+
+var fred = auth.User{Name: "fred", Groups: []string{"operator"}}
+
+func TestNewRecord(t *testing.T) {
+	rec := NewRecord(fred, testInvocation())
+	if !rec.Privileged || rec.Local {
+		t.Errorf("Unexpected record flags: %+v\n", rec)
+	}
+	if rec.Path != "set user fred password ****" {
+		t.Errorf("Unexpected masked path: %s\n", rec.Path)
+	}
+}
+
+func TestRecordMasked(t *testing.T) {
+	var seen []string
+	a := Func(func(ctx context.Context, rec *Record) error {
+		seen = append(seen, fmt.Sprintf("%#v", *rec))
+		return nil
+	})
+
+	inv := testInvocation()
+	rec := NewRecord(fred, inv)
+	// The record holds no reference to the invocation's path
+	inv.Path[4] = "changed"
+	if err := Run(context.Background(), a, rec, func() (int, error) {
+		return 0, nil
+	}); err != nil {
+		t.Fatalf("Unexpected accounting failure: %s\n", err)
+	}
+	if len(seen) != 1 || strings.Contains(seen[0], "s3cret") ||
+		strings.Contains(seen[0], "changed") {
+		t.Fatalf("Accounter saw an unmasked record: %v\n", seen)
+	}
+}
+
+func TestRun(t *testing.T) {
+	var got *Record
+	a := Func(func(ctx context.Context, rec *Record) error {
+		got = rec
+		return nil
+	})
+
+	rec := NewRecord(fred, testInvocation())
+	failure := errors.New("command failed")
+	err := Run(context.Background(), a, rec, func() (int, error) {
+		return 2, failure
+	})
+	if err != failure {
+		t.Fatalf("Expected command failure, got %v\n", err)
+	}
+	if got != rec || rec.ExitStatus != 2 {
+		t.Fatalf("Unexpected record: %+v\n", got)
+	}
+	if rec.Start.IsZero() || rec.Stop.Before(rec.Start) {
+		t.Fatalf("Unexpected times: %v - %v\n", rec.Start, rec.Stop)
+	}
+}
+
+func readEntries(t *testing.T, name string) []entry {
+	t.Helper()
+
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatalf("Unable to open log: %s\n", err)
+	}
+	defer f.Close()
+
+	var entries []entry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("Invalid log line '%s': %s\n", scanner.Text(), err)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestFileSink(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "accounting.log")
+	s, err := NewFileSink(name)
+	if err != nil {
+		t.Fatalf("Unexpected open failure: %s\n", err)
+	}
+	defer s.Close()
+
+	rec := NewRecord(fred, testInvocation())
+	if err := Run(context.Background(), s, rec, func() (int, error) {
+		return 0, nil
+	}); err != nil {
+		t.Fatalf("Unexpected accounting failure: %s\n", err)
+	}
+
+	entries := readEntries(t, name)
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %v\n", entries)
+	}
+	e := entries[0]
+	if e.Path != "set user fred password ****" || e.User != "fred" ||
+		!e.Privileged || !e.Start.Equal(rec.Start) {
+		t.Fatalf("Unexpected entry: %+v\n", e)
+	}
+}
+
+func TestFileSinkRotate(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "accounting.log")
+	s, err := NewFileSink(name)
+	if err != nil {
+		t.Fatalf("Unexpected open failure: %s\n", err)
+	}
+	defer s.Close()
+	s.MaxSize = 1
+	s.MaxBackups = 2
+
+	rec := NewRecord(fred, testInvocation())
+	for status := 0; status < 4; status++ {
+		rec.ExitStatus = status
+		if err := s.Account(context.Background(), rec); err != nil {
+			t.Fatalf("Unexpected accounting failure: %s\n", err)
+		}
+	}
+
+	for file, status := range map[string]int{
+		name:        3,
+		name + ".1": 2,
+		name + ".2": 1,
+	} {
+		entries := readEntries(t, file)
+		if len(entries) != 1 || entries[0].ExitStatus != status {
+			t.Errorf("Unexpected entries in %s: %+v\n", file, entries)
+		}
+	}
+	if _, err := os.Stat(name + ".3"); !os.IsNotExist(err) {
+		t.Errorf("Expected only 2 backups to be kept\n")
+	}
+}
+
+func TestFileSinkRotateFailure(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "accounting.log")
+	s, err := NewFileSink(name)
+	if err != nil {
+		t.Fatalf("Unexpected open failure: %s\n", err)
+	}
+	defer s.Close()
+	s.MaxSize = 1
+	s.MaxBackups = 1
+
+	// A non-empty directory in place of the backup can be neither
+	// removed nor replaced, so the log cannot be renamed
+	if err := os.MkdirAll(filepath.Join(name+".1", "busy"), 0700); err != nil {
+		t.Fatalf("Unable to create directory: %s\n", err)
+	}
+
+	rec := NewRecord(fred, testInvocation())
+	if err := s.Account(context.Background(), rec); err != nil {
+		t.Fatalf("Unexpected accounting failure: %s\n", err)
+	}
+	rec.ExitStatus = 1
+	if err := s.Account(context.Background(), rec); err == nil {
+		t.Fatalf("Expected rotation to fail\n")
+	}
+	rec.ExitStatus = 2
+	if err := s.Account(context.Background(), rec); err == nil {
+		t.Fatalf("Expected rotation to fail again\n")
+	}
+
+	entries := readEntries(t, name)
+	if len(entries) != 3 {
+		t.Fatalf("Expected records to be kept in the original log: %+v\n",
+			entries)
+	}
+	for i, e := range entries {
+		if e.ExitStatus != i {
+			t.Errorf("Unexpected entry %d: %+v\n", i, e)
+		}
+	}
+
+	// Once the obstruction is gone rotation succeeds
+	if err := os.RemoveAll(name + ".1"); err != nil {
+		t.Fatalf("Unable to remove directory: %s\n", err)
+	}
+	rec.ExitStatus = 3
+	if err := s.Account(context.Background(), rec); err != nil {
+		t.Fatalf("Unexpected accounting failure: %s\n", err)
+	}
+	if entries := readEntries(t, name); len(entries) != 1 ||
+		entries[0].ExitStatus != 3 {
+		t.Errorf("Unexpected entries after rotation: %+v\n", entries)
+	}
+	if entries := readEntries(t, name+".1"); len(entries) != 3 {
+		t.Errorf("Unexpected entries in backup: %+v\n", entries)
+	}
+}
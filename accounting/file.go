@@ -0,0 +1,162 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package accounting
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxSize is the size a log file may reach before rotation
+	DefaultMaxSize = 10 * 1024 * 1024
+	// DefaultMaxBackups is the number of rotated log files kept
+	DefaultMaxBackups = 5
+)
+
+// entry is the JSON form of a Record
+type entry struct {
+	Path       string    `json:"path"`
+	User       string    `json:"user"`
+	Groups     []string  `json:"groups,omitempty"`
+	Start      time.Time `json:"start"`
+	Stop       time.Time `json:"stop"`
+	ExitStatus int       `json:"exit-status"`
+	Privileged bool      `json:"privileged"`
+	Local      bool      `json:"local"`
+}
+
+// FileSink is an Accounter writing one JSON object per line to a local
+// file. When the file would grow beyond MaxSize it is renamed with a
+// .1 suffix, older files shifting up, and at most MaxBackups are kept.
+// If rotation fails the record is still written, to the original file,
+// and the error returned; rotation is tried again on the next record.
+// It is safe for concurrent use.
+type FileSink struct {
+	MaxSize    int64
+	MaxBackups int
+
+	mu     sync.Mutex
+	name   string
+	f      *os.File
+	size   int64
+	closed bool
+}
+
+// NewFileSink opens, creating if needed, the log file name
+func NewFileSink(name string) (*FileSink, error) {
+	s := &FileSink{
+		MaxSize:    DefaultMaxSize,
+		MaxBackups: DefaultMaxBackups,
+		name:       name,
+	}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileSink) open() error {
+	f, err := os.OpenFile(s.name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	s.f, s.size = f, fi.Size()
+	return nil
+}
+
+func (s *FileSink) backup(n int) string {
+	return fmt.Sprintf("%s.%d", s.name, n)
+}
+
+// rotate shifts the log file to the first backup and opens a new one.
+// The log is reopened whatever the outcome, so that if the files could
+// not be shifted records continue to be appended to the original.
+func (s *FileSink) rotate() error {
+	err := s.f.Close()
+	s.f = nil
+	if err == nil {
+		err = s.shift()
+	}
+	if oerr := s.open(); err == nil {
+		err = oerr
+	}
+	return err
+}
+
+func (s *FileSink) shift() error {
+	if s.MaxBackups > 0 {
+		os.Remove(s.backup(s.MaxBackups))
+		for n := s.MaxBackups - 1; n > 0; n-- {
+			os.Rename(s.backup(n), s.backup(n+1))
+		}
+		return os.Rename(s.name, s.backup(1))
+	}
+	return os.Remove(s.name)
+}
+
+func (s *FileSink) Account(ctx context.Context, rec *Record) error {
+	line, err := json.Marshal(entry{
+		Path:       rec.Path,
+		User:       rec.User.Name,
+		Groups:     rec.User.Groups,
+		Start:      rec.Start,
+		Stop:       rec.Stop,
+		ExitStatus: rec.ExitStatus,
+		Privileged: rec.Privileged,
+		Local:      rec.Local,
+	})
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return os.ErrClosed
+	}
+	var rerr error
+	if s.MaxSize > 0 && s.size > 0 && s.size+int64(len(line)) > s.MaxSize {
+		rerr = s.rotate()
+	}
+	if s.f == nil {
+		// A previous rotation failed to reopen the log
+		if err := s.open(); err != nil {
+			return err
+		}
+	}
+	n, err := s.f.Write(line)
+	s.size += int64(n)
+	if err != nil {
+		return err
+	}
+	return rerr
+}
+
+// Close closes the log file
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Close()
+	s.f = nil
+	return err
+}
@@ -0,0 +1,275 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+/*
+Package alias provides user-defined command aliases, such as "sir" for
+"show ip route".
+
+An alias is a single word standing for a command path. The path may
+refer to the words following the alias on the command line as $1, $2
+and so on, or to all of them as $@. Words which are not referred to
+are appended to the path, so "sir 10.0.0.0/8" runs
+"show ip route 10.0.0.0/8".
+
+Aliases are only recognised as the first word of a command line, and
+must be typed in full. An alias may never shadow a command: one whose
+name is, or abbreviates, the name of a command is rejected. Where the
+commands are drawn from more than one source, the top level of each is
+carried in the request's context by WithCommands, so that every source
+checks an alias against all of them.
+*/
+package alias
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Alias is a name standing for a command path
+type Alias struct {
+	Name      string
+	Expansion []string
+	Help      string
+}
+
+// params returns the highest positional parameter used and whether $@
+// is used
+func (a *Alias) params() (int, bool) {
+	max, all := 0, false
+	for _, e := range a.Expansion {
+		if e == "$@" {
+			all = true
+		} else if n, ok := param(e); ok && n > max {
+			max = n
+		}
+	}
+	return max, all
+}
+
+func param(e string) (int, bool) {
+	if !strings.HasPrefix(e, "$") {
+		return 0, false
+	}
+	n, err := strconv.Atoi(e[1:])
+	if err != nil || n < 1 {
+		return 0, false
+	}
+	return n, true
+}
+
+// Expand returns the command path for the alias given args. Unless $@
+// is used, the args not referred to are appended in order.
+func (a *Alias) Expand(args []string) ([]string, error) {
+	max, all := a.params()
+	if len(args) < max {
+		return nil, &MissingParamError{Alias: a.Name, Param: len(args) + 1}
+	}
+
+	var path []string
+	used := make([]bool, len(args))
+	for _, e := range a.Expansion {
+		if e == "$@" {
+			path = append(path, args...)
+		} else if n, ok := param(e); ok {
+			path = append(path, args[n-1])
+			used[n-1] = true
+		} else {
+			path = append(path, e)
+		}
+	}
+	if !all {
+		for i, arg := range args {
+			if !used[i] {
+				path = append(path, arg)
+			}
+		}
+	}
+	return path, nil
+}
+
+// ShadowError is returned for an alias whose name would hide commands
+type ShadowError struct {
+	Alias    string
+	Commands []string
+}
+
+func (e *ShadowError) Error() string {
+	return fmt.Sprintf("Alias %s shadows command: %s",
+		e.Alias, strings.Join(e.Commands, ", "))
+}
+
+// MissingParamError is returned when an alias is used without enough
+// words to fill its positional parameters
+type MissingParamError struct {
+	Alias string
+	Param int
+}
+
+func (e *MissingParamError) Error() string {
+	return fmt.Sprintf("Incomplete command: %s requires parameter $%d",
+		e.Alias, e.Param)
+}
+
+// shadowed returns those of commands which name would hide
+func shadowed(name string, commands []string) []string {
+	var found []string
+	for _, c := range commands {
+		if strings.HasPrefix(c, name) {
+			found = append(found, c)
+		}
+	}
+	sort.Strings(found)
+	return found
+}
+
+// Table is a set of aliases
+type Table struct {
+	aliases map[string]*Alias
+}
+
+// NewTable returns an empty table
+func NewTable() *Table {
+	return &Table{aliases: make(map[string]*Alias)}
+}
+
+// Add adds a to the table, replacing any alias of the same name. It
+// fails if a would shadow any of commands, the top level commands.
+func (t *Table) Add(a *Alias, commands []string) error {
+	if a.Name == "" || strings.ContainsAny(a.Name, " \t$") {
+		return fmt.Errorf("Invalid alias name: %q", a.Name)
+	}
+	if len(a.Expansion) == 0 {
+		return fmt.Errorf("Alias %s has no expansion", a.Name)
+	}
+	if found := shadowed(a.Name, commands); len(found) > 0 {
+		return &ShadowError{Alias: a.Name, Commands: found}
+	}
+	t.aliases[a.Name] = a
+	return nil
+}
+
+// Get returns the alias called name
+func (t *Table) Get(name string) (*Alias, bool) {
+	if t == nil {
+		return nil, false
+	}
+	a, ok := t.aliases[name]
+	return a, ok
+}
+
+// names returns the names of the aliases, sorted
+func (t *Table) names() []string {
+	names := make([]string, 0, len(t.aliases))
+	for name := range t.aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Completions returns the name of each alias with its help text
+func (t *Table) Completions() map[string]string {
+	comps := make(map[string]string)
+	if t == nil {
+		return comps
+	}
+	for _, name := range t.names() {
+		a := t.aliases[name]
+		help := a.Help
+		if help == "" {
+			help = "Alias for " + strings.Join(a.Expansion, " ")
+		}
+		comps[name] = help
+	}
+	return comps
+}
+
+// Rewrite replaces an alias at the start of path with its expansion.
+// Paths not starting with an alias are returned unchanged. commands
+// are the top level commands currently available; if the alias would
+// now shadow one of them a *ShadowError is returned rather than either
+// being chosen silently.
+func (t *Table) Rewrite(path []string, commands []string) ([]string, error) {
+	if len(path) == 0 {
+		return path, nil
+	}
+	a, ok := t.Get(path[0])
+	if !ok {
+		return path, nil
+	}
+	if found := shadowed(a.Name, commands); len(found) > 0 {
+		return nil, &ShadowError{Alias: a.Name, Commands: found}
+	}
+	return a.Expand(path[1:])
+}
+
+// Parse reads aliases, one per line, of the form
+//
+//	<name> <word>... [-- <help>]
+//
+// checking each against commands. Blank lines and lines starting
+// with '#' are ignored.
+func Parse(r io.Reader, commands []string) (*Table, error) {
+	t := NewTable()
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		var help string
+		if i := strings.Index(text, " -- "); i >= 0 {
+			text, help = text[:i], strings.TrimSpace(text[i+len(" -- "):])
+		}
+		fields := strings.Fields(text)
+		a := &Alias{Name: fields[0], Expansion: fields[1:], Help: help}
+		if err := t.Add(a, commands); err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+type tableKey struct{}
+
+// NewContext returns a copy of ctx carrying t
+func NewContext(ctx context.Context, t *Table) context.Context {
+	return context.WithValue(ctx, tableKey{}, t)
+}
+
+// FromContext returns the table carried by ctx, or nil
+func FromContext(ctx context.Context) *Table {
+	if ctx == nil {
+		return nil
+	}
+	t, _ := ctx.Value(tableKey{}).(*Table)
+	return t
+}
+
+type commandsKey struct{}
+
+// WithCommands returns a copy of ctx carrying commands, the top level
+// commands of every source a request may be expanded against
+func WithCommands(ctx context.Context, commands []string) context.Context {
+	return context.WithValue(ctx, commandsKey{}, commands)
+}
+
+// Commands returns the top level commands carried by ctx, or nil
+func Commands(ctx context.Context) []string {
+	if ctx == nil {
+		return nil
+	}
+	commands, _ := ctx.Value(commandsKey{}).([]string)
+	return commands
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package alias
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var commands = []string{"show", "ping", "reset"}
+
+const testAliases = `# Common shortcuts
+sir show ip route -- Show IP routes
+bgpsum show protocols bgp summary
+pingc ping $2 count $1
+trace traceroute $2
+intf show interfaces $1 $@
+`
+
+func getTable(t *testing.T) *Table {
+	t.Helper()
+
+	tbl, err := Parse(strings.NewReader(testAliases), commands)
+	if err != nil {
+		t.Fatalf("Unexpected parse failure: %s\n", err)
+	}
+	return tbl
+}
+
+func TestRewrite(t *testing.T) {
+	tbl := getTable(t)
+
+	tests := []struct {
+		path   string
+		expect string
+	}{
+		{"sir", "show ip route"},
+		{"sir 10.0.0.0/8", "show ip route 10.0.0.0/8"},
+		{"bgpsum", "show protocols bgp summary"},
+		{"pingc 5 host", "ping host count 5"},
+		{"pingc 5 host size 100", "ping host count 5 size 100"},
+		{"trace 5 host", "traceroute host 5"},
+		{"trace 5 host wait 2", "traceroute host 5 wait 2"},
+		{"intf dp0s3 brief", "show interfaces dp0s3 dp0s3 brief"},
+		{"show version", "show version"},
+		{"si", "si"},
+	}
+
+	for _, test := range tests {
+		got, err := tbl.Rewrite(strings.Fields(test.path), commands)
+		if err != nil {
+			t.Errorf("Unexpected failure rewriting '%s': %s\n", test.path, err)
+			continue
+		}
+		if strings.Join(got, " ") != test.expect {
+			t.Errorf("Rewriting '%s':\n Expected - %s\n Got - %v\n",
+				test.path, test.expect, got)
+		}
+	}
+
+	_, err := tbl.Rewrite([]string{"pingc", "5"}, commands)
+	if _, ok := err.(*MissingParamError); !ok {
+		t.Errorf("Expected missing parameter, got %v\n", err)
+	}
+}
+
+func TestShadow(t *testing.T) {
+	tbl := NewTable()
+
+	for _, name := range []string{"show", "sh", "p"} {
+		err := tbl.Add(&Alias{Name: name, Expansion: []string{"reset"}}, commands)
+		if _, ok := err.(*ShadowError); !ok {
+			t.Errorf("Expected alias %s to be rejected, got %v\n", name, err)
+		}
+	}
+
+	// A command added after the alias is not silently shadowed
+	if err := tbl.Add(&Alias{Name: "sir", Expansion: []string{"show"}}, commands); err != nil {
+		t.Fatalf("Unexpected failure: %s\n", err)
+	}
+	_, err := tbl.Rewrite([]string{"sir"}, append(commands, "sirens"))
+	shadow, ok := err.(*ShadowError)
+	if !ok || !reflect.DeepEqual(shadow.Commands, []string{"sirens"}) {
+		t.Errorf("Expected shadow error, got %v\n", err)
+	}
+}
+
+func TestCompletions(t *testing.T) {
+	comps := getTable(t).Completions()
+	if comps["sir"] != "Show IP routes" ||
+		comps["bgpsum"] != "Alias for show protocols bgp summary" ||
+		len(comps) != 5 {
+		t.Errorf("Unexpected completions: %v\n", comps)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	if Commands(context.Background()) != nil {
+		t.Fatalf("Expected no commands by default\n")
+	}
+	ctx := WithCommands(context.Background(), commands)
+	if !reflect.DeepEqual(Commands(ctx), commands) {
+		t.Fatalf("Commands not carried by context: %v\n", Commands(ctx))
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+/*
+Package auth defines how operational mode commands are authorised.
+An Authoriser is asked, for a given user and operation, whether a
+command path may be used and returns a decision with a reason.
+Failure of the authoriser itself is reported separately, as an
+error, so that it can be told apart from a command being denied.
+*/
+package auth
+
+import (
+	"context"
+)
+
+// Operation is the kind of access being requested for a command path
+type Operation int
+
+const (
+	// Complete is listing a path as a possible completion
+	Complete Operation = iota
+	// Expand is expanding a partially typed path element
+	Expand
+	// Execute is running the command at a path
+	Execute
+)
+
+func (o Operation) String() string {
+	switch o {
+	case Complete:
+		return "complete"
+	case Expand:
+		return "expand"
+	case Execute:
+		return "execute"
+	}
+	return "unknown"
+}
+
+// User identifies who a request is being made on behalf of
+type User struct {
+	Name   string
+	Groups []string
+}
+
+type userKey struct{}
+
+// NewContext returns a copy of ctx carrying user
+func NewContext(ctx context.Context, user User) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// UserFromContext returns the user carried by ctx, if any
+func UserFromContext(ctx context.Context) (User, bool) {
+	u, ok := ctx.Value(userKey{}).(User)
+	return u, ok
+}
+
+// Request is a single authorisation query
+type Request struct {
+	User User
+	Op   Operation
+	Path Path
+}
+
+// Decision is the outcome of an authorisation query. Reason explains
+// the decision, and is intended for logging or for display to the user.
+type Decision struct {
+	Permit bool
+	Reason string
+}
+
+// Authoriser decides whether a user may perform an operation on a
+// command path. A non-nil error means no decision could be made.
+type Authoriser interface {
+	Authorise(ctx context.Context, req Request) (Decision, error)
+}
+
+// Func adapts a plain path authorisation function to the Authoriser
+// interface. The user and operation are not passed on, and each call
+// is given its own copy of the path. A nil Func permits everything.
+type Func func(path []string) (bool, error)
+
+func (f Func) Authorise(ctx context.Context, req Request) (Decision, error) {
+	if f == nil {
+		return Decision{Permit: true}, nil
+	}
+	ok, err := f(req.Path.Elems())
+	switch {
+	case err != nil:
+		return Decision{}, err
+	case !ok:
+		return Decision{Reason: "denied by authoriser"}, nil
+	}
+	return Decision{Permit: true}, nil
+}
+
+// Error is returned when an operation could not be completed because
+// the authoriser failed to make a decision
+type Error struct {
+	Path Path
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return "Authorisation failed: " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestFuncAdapter(t *testing.T) {
+	var nilFunc Func
+	d, err := nilFunc.Authorise(context.Background(), Request{Path: NewPath("show")})
+	if err != nil || !d.Permit {
+		t.Fatalf("Expected nil Func to permit, got %v, %v", d, err)
+	}
+
+	allowShow := Func(func(path []string) (bool, error) {
+		return len(path) > 0 && path[0] == "show", nil
+	})
+	d, err = allowShow.Authorise(context.Background(), Request{Path: NewPath("show")})
+	if err != nil || !d.Permit {
+		t.Fatalf("Expected show to be permitted, got %v, %v", d, err)
+	}
+	d, err = allowShow.Authorise(context.Background(), Request{Path: NewPath("reboot")})
+	if err != nil || d.Permit || d.Reason == "" {
+		t.Fatalf("Expected reboot to be denied with a reason, got %v, %v", d, err)
+	}
+
+	failure := errors.New("server unavailable")
+	failing := Func(func(path []string) (bool, error) {
+		return true, failure
+	})
+	d, err = failing.Authorise(context.Background(), Request{Path: NewPath("show")})
+	if err != failure || d.Permit {
+		t.Fatalf("Expected authoriser failure, got %v, %v", d, err)
+	}
+}
+
+func TestUserContext(t *testing.T) {
+	if _, ok := UserFromContext(context.Background()); ok {
+		t.Fatalf("Unexpected user in empty context")
+	}
+
+	user := User{Name: "fred", Groups: []string{"operator"}}
+	u, ok := UserFromContext(NewContext(context.Background(), user))
+	if !ok || u.Name != "fred" || len(u.Groups) != 1 || u.Groups[0] != "operator" {
+		t.Fatalf("Unexpected user from context: %v", u)
+	}
+}
+
+func TestErrorUnwrap(t *testing.T) {
+	failure := errors.New("server unavailable")
+	var err error = &Error{Path: NewPath("show"), Err: failure}
+	if !errors.Is(err, failure) {
+		t.Fatalf("Expected authorisation error to wrap its cause")
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package auth
+
+import (
+	"context"
+)
+
+// BatchAuthoriser is implemented by Authorisers which can decide on
+// several children of a path in a single call. req.Path is the parent
+// path; the returned map holds a decision for each child.
+type BatchAuthoriser interface {
+	Authoriser
+	AuthoriseChildren(
+		ctx context.Context,
+		req Request,
+		children []string,
+	) (map[string]Decision, error)
+}
+
+// AuthoriseChildren decides on each child of req.Path, in a single call
+// if a is a BatchAuthoriser or one call per child otherwise.
+func AuthoriseChildren(
+	ctx context.Context,
+	a Authoriser,
+	req Request,
+	children []string,
+) (map[string]Decision, error) {
+	if b, ok := a.(BatchAuthoriser); ok {
+		return b.AuthoriseChildren(ctx, req, children)
+	}
+
+	decisions := make(map[string]Decision, len(children))
+	for _, child := range children {
+		creq := req
+		creq.Path = req.Path.Child(child)
+		d, err := a.Authorise(ctx, creq)
+		if err != nil {
+			return nil, err
+		}
+		decisions[child] = d
+	}
+	return decisions, nil
+}
+
+// Cache remembers the decisions made by an Authoriser so that each
+// path is only decided once. A Cache is intended to last for a single
+// request, and so for a single user; it is not safe for concurrent use.
+type Cache struct {
+	auth      Authoriser
+	decisions map[string]Decision
+}
+
+// NewCache returns an empty decision cache in front of a
+func NewCache(a Authoriser) *Cache {
+	return &Cache{auth: a, decisions: make(map[string]Decision)}
+}
+
+func cacheKey(op Operation, path Path) string {
+	return op.String() + "\x00" + path.key()
+}
+
+func (c *Cache) Authorise(ctx context.Context, req Request) (Decision, error) {
+	key := cacheKey(req.Op, req.Path)
+	if d, ok := c.decisions[key]; ok {
+		return d, nil
+	}
+	d, err := c.auth.Authorise(ctx, req)
+	if err != nil {
+		return d, err
+	}
+	c.decisions[key] = d
+	return d, nil
+}
+
+// AuthoriseChildren returns cached decisions where available and asks
+// the underlying authoriser about the remaining children together.
+func (c *Cache) AuthoriseChildren(
+	ctx context.Context,
+	req Request,
+	children []string,
+) (map[string]Decision, error) {
+	decisions := make(map[string]Decision, len(children))
+	var uncached []string
+	for _, child := range children {
+		if d, ok := c.decisions[cacheKey(req.Op, req.Path.Child(child))]; ok {
+			decisions[child] = d
+		} else {
+			uncached = append(uncached, child)
+		}
+	}
+	if len(uncached) == 0 {
+		return decisions, nil
+	}
+
+	fresh, err := AuthoriseChildren(ctx, c.auth, req, uncached)
+	if err != nil {
+		return nil, err
+	}
+	for _, child := range uncached {
+		d := fresh[child]
+		c.decisions[cacheKey(req.Op, req.Path.Child(child))] = d
+		decisions[child] = d
+	}
+	return decisions, nil
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+type countingAuthoriser struct {
+	calls int
+}
+
+func (c *countingAuthoriser) Authorise(ctx context.Context, req Request) (Decision, error) {
+	c.calls++
+	return Decision{Permit: !strings.HasPrefix(req.Path.Last(), "x")}, nil
+}
+
+type countingBatchAuthoriser struct {
+	countingAuthoriser
+	batches int
+}
+
+func (c *countingBatchAuthoriser) AuthoriseChildren(
+	ctx context.Context,
+	req Request,
+	children []string,
+) (map[string]Decision, error) {
+	c.batches++
+	ds := make(map[string]Decision)
+	for _, child := range children {
+		ds[child] = Decision{Permit: !strings.HasPrefix(child, "x")}
+	}
+	return ds, nil
+}
+
+func checkDecisions(t *testing.T, ds map[string]Decision, expect map[string]bool) {
+	t.Helper()
+	if len(ds) != len(expect) {
+		t.Fatalf("Unexpected decisions: %v", ds)
+	}
+	for k, v := range expect {
+		if ds[k].Permit != v {
+			t.Fatalf("Unexpected decision for %s: %v", k, ds[k])
+		}
+	}
+}
+
+func TestAuthoriseChildrenFallback(t *testing.T) {
+	ca := &countingAuthoriser{}
+	req := Request{Op: Complete, Path: NewPath("show")}
+	ds, err := AuthoriseChildren(context.Background(), ca, req,
+		[]string{"interfaces", "xyz", "version"})
+	if err != nil {
+		t.Fatalf("Unexpected failure: %s", err)
+	}
+	checkDecisions(t, ds, map[string]bool{
+		"interfaces": true, "xyz": false, "version": true})
+	if ca.calls != 3 {
+		t.Fatalf("Expected 3 calls, got %d", ca.calls)
+	}
+}
+
+func TestCacheBatches(t *testing.T) {
+	ba := &countingBatchAuthoriser{}
+	c := NewCache(ba)
+	ctx := context.Background()
+	req := Request{Op: Complete, Path: NewPath("show")}
+
+	ds, err := c.AuthoriseChildren(ctx, req, []string{"interfaces", "xyz"})
+	if err != nil {
+		t.Fatalf("Unexpected failure: %s", err)
+	}
+	checkDecisions(t, ds, map[string]bool{"interfaces": true, "xyz": false})
+
+	ds, err = c.AuthoriseChildren(ctx, req, []string{"interfaces", "xyz", "version"})
+	if err != nil {
+		t.Fatalf("Unexpected failure: %s", err)
+	}
+	checkDecisions(t, ds, map[string]bool{
+		"interfaces": true, "xyz": false, "version": true})
+	if ba.batches != 2 || ba.calls != 0 {
+		t.Fatalf("Expected 2 batches and no single calls, got %d and %d",
+			ba.batches, ba.calls)
+	}
+
+	// Single decisions are served from the cache too
+	d, err := c.Authorise(ctx, Request{Op: Complete, Path: NewPath("show", "version")})
+	if err != nil || !d.Permit {
+		t.Fatalf("Unexpected decision: %v, %v", d, err)
+	}
+	if ba.calls != 0 {
+		t.Fatalf("Expected cached decision, got %d calls", ba.calls)
+	}
+
+	// Different operations are decided separately
+	req.Op = Execute
+	if _, err := c.AuthoriseChildren(ctx, req, []string{"version"}); err != nil {
+		t.Fatalf("Unexpected failure: %s", err)
+	}
+	if ba.batches != 3 {
+		t.Fatalf("Expected a new batch for a different operation, got %d", ba.batches)
+	}
+}
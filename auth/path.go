@@ -0,0 +1,80 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package auth
+
+import (
+	"strings"
+)
+
+// Path is an immutable command path. Paths are built by copying, so an
+// Authoriser may keep a Path it is given, for caching or auditing,
+// without it changing underneath it.
+type Path struct {
+	elems []string
+}
+
+// NewPath returns a Path holding a copy of elems
+func NewPath(elems ...string) Path {
+	if len(elems) == 0 {
+		return Path{}
+	}
+	p := make([]string, len(elems))
+	copy(p, elems)
+	return Path{elems: p}
+}
+
+// Len returns the number of elements in the path
+func (p Path) Len() int {
+	return len(p.elems)
+}
+
+// Elem returns the i'th element of the path
+func (p Path) Elem(i int) string {
+	return p.elems[i]
+}
+
+// Last returns the final element of the path, or "" for an empty path
+func (p Path) Last() string {
+	if len(p.elems) == 0 {
+		return ""
+	}
+	return p.elems[len(p.elems)-1]
+}
+
+// Elems returns a copy of the path's elements
+func (p Path) Elems() []string {
+	e := make([]string, len(p.elems))
+	copy(e, p.elems)
+	return e
+}
+
+// Child returns a new path with child appended
+func (p Path) Child(child string) Path {
+	e := make([]string, len(p.elems), len(p.elems)+1)
+	copy(e, p.elems)
+	return Path{elems: append(e, child)}
+}
+
+// Equal reports whether p and q hold the same elements
+func (p Path) Equal(q Path) bool {
+	if len(p.elems) != len(q.elems) {
+		return false
+	}
+	for i := range p.elems {
+		if p.elems[i] != q.elems[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (p Path) String() string {
+	return strings.Join(p.elems, " ")
+}
+
+// key returns a string uniquely identifying the path
+func (p Path) key() string {
+	return strings.Join(p.elems, "\x00")
+}
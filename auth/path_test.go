@@ -0,0 +1,56 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package auth
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPathImmutable(t *testing.T) {
+	elems := make([]string, 2, 8)
+	elems[0], elems[1] = "show", "interfaces"
+
+	p := NewPath(elems...)
+	elems[1] = "log"
+	if p.String() != "show interfaces" {
+		t.Fatalf("Path changed with its source slice: %s", p)
+	}
+
+	a := p.Child("dataplane")
+	b := p.Child("loopback")
+	if a.String() != "show interfaces dataplane" ||
+		b.String() != "show interfaces loopback" {
+		t.Fatalf("Children share storage: %s, %s", a, b)
+	}
+
+	e := a.Elems()
+	e[0] = "reset"
+	if a.Elem(0) != "show" {
+		t.Fatalf("Path changed through Elems: %s", a)
+	}
+
+	if !p.Equal(NewPath("show", "interfaces")) || p.Equal(a) {
+		t.Fatalf("Unexpected path equality")
+	}
+	if p.Last() != "interfaces" || (Path{}).Last() != "" {
+		t.Fatalf("Unexpected last element")
+	}
+}
+
+func TestFuncGetsOwnCopy(t *testing.T) {
+	var kept []string
+	f := Func(func(path []string) (bool, error) {
+		kept = path
+		path[0] = "mutated"
+		return true, nil
+	})
+
+	req := Request{Path: NewPath("show", "version")}
+	f.Authorise(context.Background(), req)
+	if req.Path.Elem(0) != "show" || kept[0] != "mutated" {
+		t.Fatalf("Func was not given its own copy of the path")
+	}
+}
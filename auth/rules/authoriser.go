@@ -0,0 +1,164 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package rules
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/danos/op/auth"
+)
+
+// DefaultCheckInterval is how often the rule file is checked for changes
+const DefaultCheckInterval = time.Second
+
+// Authoriser decides requests using the rules in a file, reloading the
+// file whenever it changes. If the file cannot be read or parsed, every
+// request fails until it is fixed, rather than the previous rules
+// continuing to apply. It is safe for concurrent use and implements
+// auth.BatchAuthoriser.
+type Authoriser struct {
+	file     string
+	interval time.Duration
+
+	mu    sync.Mutex
+	rules *Ruleset
+	// err is the failure to load the file as it was last seen, with
+	// modTime and size recording the file whether or not it loaded
+	err       error
+	modTime   time.Time
+	size      int64
+	lastCheck time.Time
+}
+
+// New loads the rules in file
+func New(file string) (*Authoriser, error) {
+	a := &Authoriser{file: file, interval: DefaultCheckInterval}
+	if err := a.load(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// SetCheckInterval changes how often the file is checked for changes.
+// An interval of zero checks on every request.
+func (a *Authoriser) SetCheckInterval(d time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.interval = d
+}
+
+// load reads the rules in the file, recording the outcome along with
+// the state of the file, so that a broken file is not read again until
+// it changes
+func (a *Authoriser) load() error {
+	a.lastCheck = time.Now()
+	a.err = a.read()
+	return a.err
+}
+
+func (a *Authoriser) read() error {
+	a.modTime, a.size = time.Time{}, -1
+	f, err := os.Open(a.file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	a.modTime, a.size = fi.ModTime(), fi.Size()
+	rs, err := Parse(f)
+	if err != nil {
+		return err
+	}
+	a.rules = rs
+	return nil
+}
+
+// ruleset returns the current rules, reloading the file if it has
+// changed since it was last read
+func (a *Authoriser) ruleset() (*Ruleset, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if time.Since(a.lastCheck) >= a.interval {
+		fi, err := os.Stat(a.file)
+		if err != nil || !fi.ModTime().Equal(a.modTime) ||
+			fi.Size() != a.size {
+			a.load()
+		} else {
+			a.lastCheck = time.Now()
+		}
+	}
+	if a.err != nil {
+		return nil, a.err
+	}
+	return a.rules, nil
+}
+
+func (a *Authoriser) Authorise(ctx context.Context, req auth.Request) (auth.Decision, error) {
+	rs, err := a.ruleset()
+	if err != nil {
+		return auth.Decision{}, err
+	}
+	return rs.Decide(req.User, req.Op, req.Path.Elems()), nil
+}
+
+func (a *Authoriser) AuthoriseChildren(
+	ctx context.Context,
+	req auth.Request,
+	children []string,
+) (map[string]auth.Decision, error) {
+	rs, err := a.ruleset()
+	if err != nil {
+		return nil, err
+	}
+	ds := make(map[string]auth.Decision, len(children))
+	path := append(req.Path.Elems(), "")
+	for _, child := range children {
+		path[len(path)-1] = child
+		ds[child] = rs.Decide(req.User, req.Op, path)
+	}
+	return ds, nil
+}
+
+// Explanation describes how a request was decided. Rule is nil if no
+// rule matched and the default applied.
+type Explanation struct {
+	Decision auth.Decision
+	Rule     *Rule
+}
+
+// Explain answers what would happen if user requested op on path,
+// and which rule would make that decision.
+func (a *Authoriser) Explain(
+	user auth.User,
+	op auth.Operation,
+	path []string,
+) (Explanation, error) {
+	rs, err := a.ruleset()
+	if err != nil {
+		return Explanation{}, err
+	}
+	return Explanation{
+		Decision: rs.Decide(user, op, path),
+		Rule:     rs.Match(user, op, path),
+	}, nil
+}
+
+// Func returns a plain path authorisation function deciding on behalf
+// of user for op. It can be used wherever a function of paths is
+// expected, such as yang.Authoriser or by template tree callers.
+func (a *Authoriser) Func(user auth.User, op auth.Operation) auth.Func {
+	return func(path []string) (bool, error) {
+		d, err := a.Authorise(context.Background(),
+			auth.Request{User: user, Op: op, Path: auth.NewPath(path...)})
+		return d.Permit, err
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+/*
+Package rules is a command authoriser driven by a local rule file.
+
+Each line of the file is a rule of the form
+
+	<permit|deny> <subject> <element>...
+
+where subject is '*', 'user:<name>' or 'group:<name>' and each element
+is matched against the corresponding element of a command path. An
+element is a shell style glob unless it starts with '~', in which case
+the remainder is a regular expression which must match the whole path
+element. The element '**' matches any number of path elements.
+
+Rules are evaluated in order and the first rule whose subject and path
+both match decides. A line of the form 'default <permit|deny>' sets
+the decision made when no rule matches; the default is deny. Blank
+lines and lines starting with '#' are ignored.
+
+When completing or expanding, a permit rule also matches paths which
+could be extended to match it, so that users can reach the commands
+they are permitted to run.
+*/
+package rules
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/danos/op/auth"
+)
+
+// Action is what a rule does with a matching request
+type Action int
+
+const (
+	Deny Action = iota
+	Permit
+)
+
+func (a Action) String() string {
+	if a == Permit {
+		return "permit"
+	}
+	return "deny"
+}
+
+func parseAction(s string) (Action, error) {
+	switch s {
+	case "permit":
+		return Permit, nil
+	case "deny":
+		return Deny, nil
+	}
+	return Deny, fmt.Errorf("invalid action %q", s)
+}
+
+// Rule is a single line of a rule file
+type Rule struct {
+	Line    int
+	Action  Action
+	Subject string
+	Pattern []string
+	elems   []*regexp.Regexp
+}
+
+func (r *Rule) String() string {
+	return fmt.Sprintf("%s %s %s",
+		r.Action, r.Subject, strings.Join(r.Pattern, " "))
+}
+
+func (r *Rule) appliesTo(user auth.User) bool {
+	switch {
+	case r.Subject == "*":
+		return true
+	case strings.HasPrefix(r.Subject, "user:"):
+		return user.Name == strings.TrimPrefix(r.Subject, "user:")
+	case strings.HasPrefix(r.Subject, "group:"):
+		group := strings.TrimPrefix(r.Subject, "group:")
+		for _, g := range user.Groups {
+			if g == group {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matches reports whether path matches the rule's pattern. If partial
+// is set, a path which could be extended to match also matches.
+func (r *Rule) matches(path []string, partial bool) bool {
+	return matchElems(r.Pattern, r.elems, path, partial)
+}
+
+func matchElems(pat []string, elems []*regexp.Regexp, path []string, partial bool) bool {
+	if len(pat) == 0 {
+		return len(path) == 0
+	}
+	if pat[0] == "**" {
+		for i := 0; i <= len(path); i++ {
+			if matchElems(pat[1:], elems[1:], path[i:], partial) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(path) == 0 {
+		return partial
+	}
+	if !elems[0].MatchString(path[0]) {
+		return false
+	}
+	return matchElems(pat[1:], elems[1:], path[1:], partial)
+}
+
+// compileElem converts a pattern element to an anchored regular
+// expression
+func compileElem(elem string) (*regexp.Regexp, error) {
+	if elem == "**" {
+		return nil, nil
+	}
+	if strings.HasPrefix(elem, "~") {
+		return regexp.Compile("^(?:" + elem[1:] + ")$")
+	}
+
+	var re strings.Builder
+	re.WriteString("^")
+	for i := 0; i < len(elem); {
+		// Step by rune so that multi-byte literals are quoted whole
+		r, size := utf8.DecodeRuneInString(elem[i:])
+		switch r {
+		case '*':
+			re.WriteString(".*")
+		case '?':
+			re.WriteString(".")
+		case '[':
+			end := strings.IndexByte(elem[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated '[' in %q", elem)
+			}
+			class := elem[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			re.WriteString("[" + class + "]")
+			size = end + 1
+		case '\\':
+			if i+size < len(elem) {
+				i += size
+				_, size = utf8.DecodeRuneInString(elem[i:])
+			}
+			re.WriteString(regexp.QuoteMeta(elem[i : i+size]))
+		default:
+			re.WriteString(regexp.QuoteMeta(string(r)))
+		}
+		i += size
+	}
+	re.WriteString("$")
+	return regexp.Compile(re.String())
+}
+
+func validSubject(s string) bool {
+	return s == "*" ||
+		(strings.HasPrefix(s, "user:") && len(s) > len("user:")) ||
+		(strings.HasPrefix(s, "group:") && len(s) > len("group:"))
+}
+
+// Ruleset is an ordered list of rules with a default action
+type Ruleset struct {
+	Rules   []*Rule
+	Default Action
+}
+
+// Parse reads a rule file
+func Parse(rd io.Reader) (*Ruleset, error) {
+	rs := &Ruleset{Default: Deny}
+	scanner := bufio.NewScanner(rd)
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		if fields[0] == "default" {
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("line %d: default takes one action", line)
+			}
+			act, err := parseAction(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", line, err)
+			}
+			rs.Default = act
+			continue
+		}
+
+		act, err := parseAction(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err)
+		}
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("line %d: rule needs a subject and a path", line)
+		}
+		if !validSubject(fields[1]) {
+			return nil, fmt.Errorf("line %d: invalid subject %q", line, fields[1])
+		}
+		r := &Rule{
+			Line:    line,
+			Action:  act,
+			Subject: fields[1],
+			Pattern: fields[2:],
+		}
+		for _, elem := range r.Pattern {
+			re, err := compileElem(elem)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", line, err)
+			}
+			r.elems = append(r.elems, re)
+		}
+		rs.Rules = append(rs.Rules, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+// Match returns the first rule deciding the request, or nil if the
+// default applies
+func (rs *Ruleset) Match(user auth.User, op auth.Operation, path []string) *Rule {
+	partial := op == auth.Complete || op == auth.Expand
+	for _, r := range rs.Rules {
+		if !r.appliesTo(user) {
+			continue
+		}
+		if r.matches(path, partial && r.Action == Permit) {
+			return r
+		}
+	}
+	return nil
+}
+
+// Decide returns the decision for the request along with the reason
+func (rs *Ruleset) Decide(user auth.User, op auth.Operation, path []string) auth.Decision {
+	r := rs.Match(user, op, path)
+	if r == nil {
+		return auth.Decision{
+			Permit: rs.Default == Permit,
+			Reason: fmt.Sprintf("no rule matched, default %s", rs.Default),
+		}
+	}
+	return auth.Decision{
+		Permit: r.Action == Permit,
+		Reason: fmt.Sprintf("rule %d: %s", r.Line, r),
+	}
+}
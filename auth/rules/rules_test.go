@@ -0,0 +1,263 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package rules
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danos/op/auth"
+)
+
+const testRules = `# operators may look but not touch
+default deny
+deny   group:operator show log **
+permit group:operator show **
+permit group:operator ping ~10\.[0-9.]+
+permit user:fred      reset interfaces dp0*
+deny   *              reset **
+`
+
+var (
+	operator = auth.User{Name: "olive", Groups: []string{"operator"}}
+	fred     = auth.User{Name: "fred"}
+)
+
+func getRuleset(t *testing.T, text string) *Ruleset {
+	t.Helper()
+
+	rs, err := Parse(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("Unexpected parse failure:\n  %s\n", err)
+	}
+	return rs
+}
+
+func TestDecide(t *testing.T) {
+	rs := getRuleset(t, testRules)
+
+	tests := []struct {
+		user   auth.User
+		op     auth.Operation
+		path   string
+		permit bool
+		line   int
+	}{
+		{operator, auth.Execute, "show interfaces", true, 4},
+		{operator, auth.Execute, "show log", false, 3},
+		{operator, auth.Execute, "show log tail 10", false, 3},
+		{operator, auth.Execute, "ping 10.0.0.1", true, 5},
+		{operator, auth.Execute, "ping 192.168.0.1", false, 0},
+		{operator, auth.Execute, "ping", false, 0},
+		{operator, auth.Complete, "ping", true, 5},
+		{operator, auth.Execute, "reset interfaces dp0s3", false, 7},
+		{fred, auth.Execute, "reset interfaces dp0s3", true, 6},
+		{fred, auth.Execute, "reset interfaces lo", false, 7},
+		{fred, auth.Expand, "reset", true, 6},
+		{fred, auth.Execute, "show interfaces", false, 0},
+	}
+
+	for _, test := range tests {
+		path := strings.Fields(test.path)
+		d := rs.Decide(test.user, test.op, path)
+		if d.Permit != test.permit {
+			t.Errorf("%s %s '%s': expected permit %v, got %v (%s)\n",
+				test.user.Name, test.op, test.path, test.permit, d.Permit, d.Reason)
+		}
+		line := 0
+		if r := rs.Match(test.user, test.op, path); r != nil {
+			line = r.Line
+		}
+		if line != test.line {
+			t.Errorf("%s %s '%s': expected rule %d, got %d\n",
+				test.user.Name, test.op, test.path, test.line, line)
+		}
+	}
+}
+
+func TestDecideNonASCII(t *testing.T) {
+	rs := getRuleset(t, "permit * show café\n")
+
+	if d := rs.Decide(operator, auth.Execute, []string{"show", "café"}); !d.Permit {
+		t.Errorf("Expected non-ASCII command to be permitted: %s\n", d.Reason)
+	}
+	if d := rs.Decide(operator, auth.Execute, []string{"show", "cafe"}); d.Permit {
+		t.Errorf("Expected non-matching command to be denied\n")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"allow * show",
+		"permit show",
+		"permit someone show",
+		"permit * show [abc",
+		"permit * ~(",
+		"default maybe",
+	}
+
+	for _, text := range tests {
+		if _, err := Parse(strings.NewReader(text)); err == nil {
+			t.Errorf("Expected parse failure for '%s'\n", text)
+		} else if !strings.HasPrefix(err.Error(), "line 1:") {
+			t.Errorf("Expected line number in error, got: %s\n", err)
+		}
+	}
+}
+
+func TestElementGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		value   string
+		match   bool
+	}{
+		{"dp0*", "dp0s3", true},
+		{"dp0*", "dp1s3", false},
+		{"*", "10.0.0.0/8", true},
+		{"eth?", "eth1", true},
+		{"eth?", "eth10", false},
+		{"eth[0-3]", "eth2", true},
+		{"eth[!0-3]", "eth2", false},
+		{`a\*`, "a*", true},
+		{`a\*`, "ab", false},
+		{"a.b", "axb", false},
+		{"café", "café", true},
+		{"caf?", "café", true},
+		{"caf?", "cafe", true},
+		{"*é", "résumé", true},
+		{`\é`, "é", true},
+		{"naïve*", "naïveté", true},
+		{"naïve*", "naive", false},
+	}
+
+	for _, test := range tests {
+		re, err := compileElem(test.pattern)
+		if err != nil {
+			t.Errorf("Unexpected failure compiling '%s': %s\n", test.pattern, err)
+			continue
+		}
+		if got := re.MatchString(test.value); got != test.match {
+			t.Errorf("'%s' against '%s': expected %v, got %v\n",
+				test.pattern, test.value, test.match, got)
+		}
+	}
+}
+
+func writeRules(t *testing.T, file, text string, mtime time.Time) {
+	t.Helper()
+
+	if err := os.WriteFile(file, []byte(text), 0644); err != nil {
+		t.Fatalf("Unable to write rules: %s\n", err)
+	}
+	if err := os.Chtimes(file, mtime, mtime); err != nil {
+		t.Fatalf("Unable to set rule file time: %s\n", err)
+	}
+}
+
+func TestAuthoriserReload(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "rules")
+	now := time.Now()
+	writeRules(t, file, "permit * show **\n", now)
+
+	a, err := New(file)
+	if err != nil {
+		t.Fatalf("Unexpected load failure: %s\n", err)
+	}
+	a.SetCheckInterval(0)
+
+	req := auth.Request{User: fred, Op: auth.Execute, Path: auth.NewPath("show", "version")}
+	if d, _ := a.Authorise(context.Background(), req); !d.Permit {
+		t.Errorf("Expected permit before reload: %s\n", d.Reason)
+	}
+
+	writeRules(t, file, "deny * show **\n", now.Add(time.Second))
+	if d, _ := a.Authorise(context.Background(), req); d.Permit {
+		t.Errorf("Expected deny after reload: %s\n", d.Reason)
+	}
+
+	writeRules(t, file, "bogus\n", now.Add(2*time.Second))
+	if _, err := a.Authorise(context.Background(), req); err == nil {
+		t.Errorf("Expected failure for invalid rule file\n")
+	}
+}
+
+func TestAuthoriserReloadBroken(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "rules")
+	now := time.Now()
+	writeRules(t, file, "permit * show **\n", now)
+
+	a, err := New(file)
+	if err != nil {
+		t.Fatalf("Unexpected load failure: %s\n", err)
+	}
+	const interval = 20 * time.Millisecond
+	a.SetCheckInterval(interval)
+
+	req := auth.Request{User: fred, Op: auth.Execute, Path: auth.NewPath("show", "version")}
+	writeRules(t, file, "bogus\n", now.Add(time.Second))
+	time.Sleep(interval)
+
+	// The broken file fails every request, not only the one reloading
+	// it, both within the interval and once it has passed again
+	for i := 0; i < 2; i++ {
+		if _, err := a.Authorise(context.Background(), req); err == nil {
+			t.Fatalf("Expected failure for invalid rule file\n")
+		}
+		if _, err := a.Authorise(context.Background(), req); err == nil {
+			t.Fatalf("Expected failure within check interval\n")
+		}
+		time.Sleep(interval)
+	}
+
+	writeRules(t, file, "deny * show **\n", now.Add(2*time.Second))
+	time.Sleep(interval)
+	if d, err := a.Authorise(context.Background(), req); err != nil || d.Permit {
+		t.Errorf("Expected deny once fixed: %v, %v\n", d, err)
+	}
+}
+
+func TestAuthoriserChildrenAndExplain(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "rules")
+	writeRules(t, file, testRules, time.Now())
+
+	a, err := New(file)
+	if err != nil {
+		t.Fatalf("Unexpected load failure: %s\n", err)
+	}
+
+	req := auth.Request{User: operator, Op: auth.Complete, Path: auth.Path{}}
+	ds, err := a.AuthoriseChildren(context.Background(), req,
+		[]string{"show", "ping", "reset"})
+	if err != nil {
+		t.Fatalf("Unexpected failure: %s\n", err)
+	}
+	if !ds["show"].Permit || !ds["ping"].Permit || ds["reset"].Permit {
+		t.Errorf("Unexpected child decisions: %v\n", ds)
+	}
+
+	exp, err := a.Explain(operator, auth.Execute, []string{"show", "log"})
+	if err != nil {
+		t.Fatalf("Unexpected failure: %s\n", err)
+	}
+	if exp.Decision.Permit || exp.Rule == nil || exp.Rule.Line != 3 {
+		t.Errorf("Unexpected explanation: %+v\n", exp)
+	}
+	if exp.Decision.Reason != "rule 3: deny group:operator show log **" {
+		t.Errorf("Unexpected reason: %s\n", exp.Decision.Reason)
+	}
+
+	exp, _ = a.Explain(fred, auth.Execute, []string{"show"})
+	if exp.Rule != nil || exp.Decision.Reason != "no rule matched, default deny" {
+		t.Errorf("Unexpected default explanation: %+v\n", exp)
+	}
+
+	if ok, _ := a.Func(fred, auth.Execute)([]string{"reset", "interfaces", "dp0s1"}); !ok {
+		t.Errorf("Expected Func to permit fred\n")
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package main
+
+import (
+	"flag"
+
+	"github.com/danos/op/cmdtree"
+	"github.com/danos/op/tmpl/tree"
+	"github.com/danos/op/yang"
+)
+
+// treeFlags are the flags of the subcommands which describe the merged
+// command tree of the templates and opd YANG
+type treeFlags struct {
+	root     *string
+	yangDir  *string
+	features *string
+}
+
+func addTreeFlags(fs *flag.FlagSet) *treeFlags {
+	return &treeFlags{
+		root: fs.String("root", "/opt/vyatta/share/vyatta-op/templates",
+			"Template root directory, or empty for none"),
+		yangDir: fs.String("yang", "",
+			"Opd YANG directory, or empty for none"),
+		features: fs.String("features", "/config/features",
+			"Directory of enabled features"),
+	}
+}
+
+// commandTree returns the command tree of the templates and opd YANG,
+// where YANG takes precedence
+func (f *treeFlags) commandTree() (*cmdtree.Node, error) {
+	var high, low *cmdtree.Node
+	if *f.yangDir != "" {
+		y, err := yang.NewYangDirs(*f.yangDir, *f.features)
+		if err != nil {
+			return nil, err
+		}
+		high = y.CommandTree()
+	}
+	if *f.root != "" {
+		t, err := tree.BuildOpTreeFeatures(*f.root, *f.features)
+		if err != nil {
+			return nil, err
+		}
+		low = cmdtree.FromTree(t)
+	}
+	if high == nil && low == nil {
+		return &cmdtree.Node{}, nil
+	}
+	return cmdtree.Merge(high, low), nil
+}
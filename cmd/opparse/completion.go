@@ -0,0 +1,37 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/danos/op/shellcomp"
+)
+
+// completion writes a shell completion script for the command tree
+func completion(args []string) error {
+	fs := flag.NewFlagSet("completion", flag.ExitOnError)
+	tf := addTreeFlags(fs)
+	shell := fs.String("shell", "bash", "Shell to complete for: bash or zsh")
+	command := fs.String("command", "",
+		"Command taking the operational commands as arguments, or empty\n"+
+			"to complete each top level command")
+	fs.Parse(args)
+
+	root, err := tf.commandTree()
+	if err != nil {
+		return err
+	}
+	s := &shellcomp.Script{Root: root, Command: *command}
+	switch *shell {
+	case "bash":
+		return s.WriteBash(os.Stdout)
+	case "zsh":
+		return s.WriteZsh(os.Stdout)
+	}
+	return fmt.Errorf("Unsupported shell: %s", *shell)
+}
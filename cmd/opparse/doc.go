@@ -0,0 +1,42 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/danos/op/docgen"
+	"github.com/danos/op/tmpl"
+)
+
+// doc writes the command reference of the command tree
+func doc(args []string) error {
+	fs := flag.NewFlagSet("doc", flag.ExitOnError)
+	tf := addTreeFlags(fs)
+	format := fs.String("format", "markdown", "Output format: markdown or man")
+	title := fs.String("title", "vyatta-op",
+		"Title of the document, or name of the man page")
+	hidden := fs.Bool("hidden", false, "Document hidden commands")
+	fs.Parse(args)
+
+	root, err := tf.commandTree()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	if *hidden {
+		ctx = tmpl.WithHidden(ctx)
+	}
+	switch *format {
+	case "markdown":
+		return docgen.WriteMarkdown(ctx, os.Stdout, *title, root)
+	case "man":
+		return docgen.WriteMan(ctx, os.Stdout, *title, root)
+	}
+	return fmt.Errorf("Unsupported format: %s", *format)
+}
@@ -10,6 +10,7 @@ package main
 import (
 	"flag"
 	"fmt"
+	"os"
 
 	"github.com/danos/op/tmpl/tree"
 )
@@ -18,8 +19,20 @@ var vyattaOpTmplDir *string = flag.String("root",
 	"/opt/vyatta/share/vyatta-op/templates",
 	"Template root directory")
 
+var subcommands = map[string]func([]string) error{
+	"completion": completion,
+	"doc":        doc,
+}
+
 func main() {
 	flag.Parse()
+	if sub, ok := subcommands[flag.Arg(0)]; ok {
+		if err := sub(flag.Args()[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 	t, err := tree.BuildOpTree(*vyattaOpTmplDir)
 	if err != nil {
 		fmt.Printf("%s\n", err)
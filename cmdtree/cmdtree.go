@@ -0,0 +1,212 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+// Package cmdtree is a neutral representation of the operational command
+// tree, built from the template tree or opd YANG, and merged from both,
+// for tools which describe the commands off-box such as completion
+// script and documentation generators.
+package cmdtree
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/danos/op/tmpl/tree"
+)
+
+// Source identifies where a node was defined
+type Source int
+
+const (
+	Template Source = iota
+	Yang
+)
+
+func (s Source) String() string {
+	if s == Yang {
+		return "yang"
+	}
+	return "template"
+}
+
+// Node is a keyword or argument of the command tree
+type Node struct {
+	// Name is the keyword, or for an argument, the name it is known by
+	Name string
+	// Arg is set for an argument, which takes any value allowed
+	Arg  bool
+	Help string
+	// Allowed is the allowed hint for an argument's values
+	Allowed string
+	// Values are the values an argument may take, if it takes a fixed
+	// set, from an enumeration or a static allowed hint
+	Values []string
+	// Description is the longer description of a YANG node
+	Description string
+	// Runnable is set for a node which runs a command when entered
+	Runnable bool
+	// Privileged is set for a command which runs with root privileges
+	Privileged bool
+	// Features are the features which must be enabled for the node to
+	// be present, as module:feature
+	Features []string
+	// Hidden is set for a command left out of completions and listings
+	Hidden bool
+	// Deprecated is the deprecation warning of a deprecated node, and
+	// Replacement the command to use instead, if any
+	Deprecated  string
+	Replacement string
+	Source      Source
+	Children    []*Node
+}
+
+// Keywords returns the children which are not arguments
+func (n *Node) Keywords() []*Node {
+	var kws []*Node
+	for _, c := range n.Children {
+		if !c.Arg {
+			kws = append(kws, c)
+		}
+	}
+	return kws
+}
+
+// Argument returns the argument child, or nil if there is none
+func (n *Node) Argument() *Node {
+	for _, c := range n.Children {
+		if c.Arg {
+			return c
+		}
+	}
+	return nil
+}
+
+// Walk calls fn for each node below n, depth first, with the nodes on
+// the path leading to it, itself included. The children of a node are
+// not walked if fn returns false for it.
+func (n *Node) Walk(fn func(path []*Node, n *Node) bool) {
+	n.walk(nil, fn)
+}
+
+func (n *Node) walk(path []*Node, fn func([]*Node, *Node) bool) {
+	for _, c := range n.Children {
+		cpath := append(path[:len(path):len(path)], c)
+		if fn(cpath, c) {
+			c.walk(cpath, fn)
+		}
+	}
+}
+
+// Sort sorts the children of n and its descendants by name, keywords
+// first
+func (n *Node) Sort() {
+	sort.SliceStable(n.Children, func(i, j int) bool {
+		a, b := n.Children[i], n.Children[j]
+		if a.Arg != b.Arg {
+			return !a.Arg
+		}
+		return a.Name < b.Name
+	})
+	for _, c := range n.Children {
+		c.Sort()
+	}
+}
+
+// FromTree returns the command tree of t, including hidden nodes,
+// which are marked as such. A tag node becomes an argument named by its
+// parent. Features disabled when t was built are already absent.
+func FromTree(t *tree.OpTree) *Node {
+	root := &Node{Name: t.Name(), Source: Template}
+	fromTree(root, t)
+	root.Sort()
+	return root
+}
+
+func fromTree(n *Node, t *tree.OpTree) {
+	for i := tree.NewChildIteratorAll(t); i.HasNext(); i.Next() {
+		c := i.Value()
+		v := c.Value()
+		cn := &Node{
+			Name:        c.Name(),
+			Help:        strings.TrimSpace(v.Help()),
+			Runnable:    v.Run() != "",
+			Privileged:  v.Run() != "" && v.Priv(),
+			Features:    splitFeatures(v.Features()),
+			Hidden:      v.Hidden(),
+			Deprecated:  v.Deprecated(),
+			Replacement: v.Replacement(),
+			Source:      Template,
+		}
+		if cn.Name == "node.tag" {
+			cn.Name, cn.Arg = t.Name(), true
+			cn.Allowed = strings.TrimSpace(v.Allowed())
+			cn.Values = StaticValues(cn.Allowed)
+		}
+		fromTree(cn, c)
+		n.Children = append(n.Children, cn)
+	}
+}
+
+// splitFeatures splits a template's semi-colon separated features
+func splitFeatures(features string) []string {
+	var feats []string
+	for _, f := range strings.Split(features, ";") {
+		if f = strings.TrimSpace(f); f != "" {
+			feats = append(feats, f)
+		}
+	}
+	return feats
+}
+
+// Merge merges low into high, where nodes of the same name, or two
+// arguments, are merged with those of high taking precedence
+func Merge(high, low *Node) *Node {
+	if high == nil {
+		return low
+	}
+	if low == nil {
+		return high
+	}
+	m := *high
+	m.Children = nil
+	used := make(map[*Node]bool)
+	for _, hc := range high.Children {
+		var lc *Node
+		for _, c := range low.Children {
+			if !used[c] && (c.Name == hc.Name && c.Arg == hc.Arg ||
+				c.Arg && hc.Arg) {
+				lc = c
+				used[c] = true
+				break
+			}
+		}
+		m.Children = append(m.Children, Merge(hc, lc))
+	}
+	for _, lc := range low.Children {
+		if !used[lc] {
+			m.Children = append(m.Children, lc)
+		}
+	}
+	m.Sort()
+	return &m
+}
+
+var staticAllowed = regexp.MustCompile(`^echo(\s+-n)?((\s+[\w.:/@+-]+)+)\s*;?$`)
+
+// StaticValues returns the values listed by an allowed hint which only
+// echoes a fixed list of words, or nil if the values can only be found
+// by running it
+func StaticValues(allowed string) []string {
+	m := staticAllowed.FindStringSubmatch(strings.TrimSpace(allowed))
+	if m == nil {
+		return nil
+	}
+	return strings.Fields(m[2])
+}
+
+// Placeholder returns how an argument is shown in syntax and hints
+func (n *Node) Placeholder() string {
+	return "<" + n.Name + ">"
+}
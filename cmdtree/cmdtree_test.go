@@ -0,0 +1,156 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package cmdtree
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/danos/op/tmpl"
+	"github.com/danos/op/tmpl/tree"
+)
+
+func buildTree(names ...string) *tree.OpTree {
+	root := tree.NewOpTree("templates", nil)
+	for _, name := range names {
+		n := root
+		for _, elem := range strings.Fields(name) {
+			c, err := n.Child(elem)
+			if err != nil {
+				c = tree.NewOpTree(elem, tmpl.NewOpTmpl("", "Help for "+elem, "", ""))
+				n.AddChild(c)
+			}
+			n = c
+		}
+	}
+	return root
+}
+
+// paths returns the paths of the nodes of n, with arguments shown as
+// placeholders and hidden nodes marked
+func paths(n *Node) []string {
+	var out []string
+	n.Walk(func(path []*Node, n *Node) bool {
+		var elems []string
+		for _, p := range path {
+			if p.Arg {
+				elems = append(elems, p.Placeholder())
+			} else {
+				elems = append(elems, p.Name)
+			}
+		}
+		s := strings.Join(elems, " ")
+		if n.Hidden {
+			s += " (hidden)"
+		}
+		out = append(out, s)
+		return true
+	})
+	return out
+}
+
+func TestFromTree(t *testing.T) {
+	ot := buildTree("show log", "show interfaces node.tag brief", "show debug")
+	dbg, _ := ot.Descendant([]string{"show", "debug"})
+	dbg.Value().SetHidden(true)
+	tag, _ := ot.Descendant([]string{"show", "interfaces", "node.tag"})
+	tag.Value().SetAllowed("echo dp0s3 dp0s4")
+
+	root := FromTree(ot)
+	expected := []string{
+		"show",
+		"show debug (hidden)",
+		"show interfaces",
+		"show interfaces <interfaces>",
+		"show interfaces <interfaces> brief",
+		"show log",
+	}
+	if got := paths(root); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected tree:\n%s\n", strings.Join(got, "\n"))
+	}
+
+	arg := root.Children[0].Children[1].Argument()
+	if arg == nil || !reflect.DeepEqual(arg.Values, []string{"dp0s3", "dp0s4"}) {
+		t.Errorf("Unexpected argument: %+v\n", arg)
+	}
+	if root.Children[0].Help != "Help for show" ||
+		root.Children[0].Source != Template {
+		t.Errorf("Unexpected node: %+v\n", root.Children[0])
+	}
+}
+
+func TestMerge(t *testing.T) {
+	high := FromTree(buildTree("show version", "show interfaces node.tag"))
+	high.Walk(func(_ []*Node, n *Node) bool {
+		n.Source = Yang
+		return true
+	})
+	low := FromTree(buildTree("show log", "show interfaces node.tag brief"))
+	low.Children[0].Children[0].Children[0].Name = "ifname"
+
+	m := Merge(high, low)
+	expected := []string{
+		"show",
+		"show interfaces",
+		"show interfaces <interfaces>",
+		"show interfaces <interfaces> brief",
+		"show log",
+		"show version",
+	}
+	if got := paths(m); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected tree:\n%s\n", strings.Join(got, "\n"))
+	}
+	sources := make(map[string]Source)
+	m.Walk(func(path []*Node, n *Node) bool {
+		sources[n.Name] = n.Source
+		return true
+	})
+	if sources["show"] != Yang || sources["log"] != Template ||
+		sources["brief"] != Template {
+		t.Errorf("Unexpected sources: %v\n", sources)
+	}
+}
+
+func TestStaticValues(t *testing.T) {
+	tests := []struct {
+		allowed  string
+		expected []string
+	}{
+		{"echo up down", []string{"up", "down"}},
+		{"echo -n ipv4 ipv6;", []string{"ipv4", "ipv6"}},
+		{"echo <1-10>", nil},
+		{"echo $(ls /sys/class/net)", nil},
+		{"ls /sys/class/net", nil},
+		{"", nil},
+	}
+	for _, test := range tests {
+		if got := StaticValues(test.allowed); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%q: expected %v, got %v\n", test.allowed, test.expected, got)
+		}
+	}
+}
+
+func TestFromTreeMetadata(t *testing.T) {
+	ot := buildTree("show log", "show version")
+	lg, _ := ot.Descendant([]string{"show", "log"})
+	lg.Value().SetRun("cat /var/log/messages")
+	lg.Value().SetPriv(true)
+	lg.Value().SetFeatures("vyatta-log:log; vyatta-log:local;")
+	lg.Value().SetDeprecated("Moved")
+	lg.Value().SetReplacement("show logging")
+
+	root := FromTree(ot)
+	show := root.Children[0]
+	n, v := show.Children[0], show.Children[1]
+	if !n.Runnable || !n.Privileged || n.Deprecated != "Moved" ||
+		n.Replacement != "show logging" ||
+		!reflect.DeepEqual(n.Features, []string{"vyatta-log:log", "vyatta-log:local"}) {
+		t.Errorf("Unexpected node: %+v\n", n)
+	}
+	if show.Runnable || v.Runnable || v.Privileged || v.Features != nil {
+		t.Errorf("Unexpected nodes: %+v, %+v\n", show, v)
+	}
+}
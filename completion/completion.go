@@ -0,0 +1,271 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+// Package completion completes the token under the cursor of a partial
+// command line, drawing candidates from YANG and template sources.
+package completion
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/danos/op/alias"
+	"github.com/danos/op/auth"
+	"github.com/danos/op/matching"
+	"github.com/danos/op/pipe"
+	"github.com/danos/op/tmpl/tree"
+	"github.com/danos/op/tokenizer"
+	"github.com/danos/op/yang"
+)
+
+// Source is a source of commands to complete
+type Source interface {
+	// Expand expands a possibly abbreviated path. A path which
+	// expands but is incomplete is not an error.
+	Expand(ctx context.Context, path []string) ([]string, error)
+	// Completion returns the names and help text of the children of
+	// an expanded path. Names in angle brackets are placeholders for
+	// an argument value.
+	Completion(ctx context.Context, path []string) (map[string]string, error)
+}
+
+type yangSource struct {
+	y *yang.Yang
+	a auth.Authoriser
+}
+
+// Yang returns a Source for the commands of y which a permits
+func Yang(y *yang.Yang, a auth.Authoriser) Source {
+	return &yangSource{y: y, a: a}
+}
+
+func (s *yangSource) Expand(ctx context.Context, path []string) ([]string, error) {
+	epath, err := s.y.ExpandContext(ctx, path, s.a)
+	if _, ok := err.(*yang.CommandIncomplete); ok {
+		return epath, nil
+	}
+	return epath, err
+}
+
+func (s *yangSource) Completion(
+	ctx context.Context,
+	path []string,
+) (map[string]string, error) {
+	return s.y.CompletionContext(ctx, path, s.a)
+}
+
+type treeSource struct {
+	t *tree.OpTree
+	a auth.Authoriser
+}
+
+// Tree returns a Source for the commands of the template tree t which a
+// permits. Aliases and matching policy carried by the context are
+// applied as they are for YANG.
+func Tree(t *tree.OpTree, a auth.Authoriser) Source {
+	return &treeSource{t: t, a: a}
+}
+
+func (s *treeSource) Expand(ctx context.Context, path []string) ([]string, error) {
+	return s.t.ExpandAliases(ctx, path, matching.FromContext(ctx),
+		alias.FromContext(ctx), s.a)
+}
+
+func (s *treeSource) Completion(
+	ctx context.Context,
+	path []string,
+) (map[string]string, error) {
+	m, err := s.t.Completion(ctx, path, s.a)
+	if err != nil || len(path) != 0 {
+		return m, err
+	}
+	for name, help := range alias.FromContext(ctx).Completions() {
+		if _, ok := m[name]; !ok {
+			m[name] = help
+		}
+	}
+	return m, nil
+}
+
+// Candidate is a possible completion
+type Candidate struct {
+	Name string
+	Help string
+}
+
+// IsPlaceholder returns true if the candidate stands for a value to be
+// typed, rather than being text to insert
+func (c Candidate) IsPlaceholder() bool {
+	return strings.HasPrefix(c.Name, "<") && strings.HasSuffix(c.Name, ">")
+}
+
+// Result is the completion of the token under the cursor
+type Result struct {
+	// Candidates are those matching the typed prefix, sorted by name.
+	// Placeholders are always included.
+	Candidates []Candidate
+	// Prefix is the value of the token typed before the cursor
+	Prefix string
+	// Common is the longest prefix common to the candidates which are
+	// not placeholders, or Prefix if it has none in common
+	Common string
+	// Start and End are the byte offsets of the token to replace with
+	// Insert, equal if the cursor was not in a token
+	Start, End int
+	Insert     string
+	// Space is set if Insert completes the token, so that a space
+	// should follow it
+	Space bool
+}
+
+// Complete completes the token under the cursor at byte offset cursor
+// in line, using the sources in order of priority. Where sources have a
+// candidate of the same name, the help of the first is used. Tokens
+// following a pipe are completed as output filters. Where ctx carries
+// aliases, it should also carry the top level commands of every source
+// by alias.WithCommands, so that no alias shadows any of them.
+func Complete(
+	ctx context.Context,
+	line string,
+	cursor int,
+	sources ...Source,
+) (*Result, error) {
+	if cursor < 0 || cursor > len(line) {
+		cursor = len(line)
+	}
+	l, err := tokenizer.Split(line, cursor)
+	if err != nil {
+		// An unterminated quote is completed as if it were closed
+		if _, ok := err.(*tokenizer.Error); !ok || !l.InToken ||
+			l.Cursor != len(l.Tokens)-1 {
+			return nil, err
+		}
+	}
+
+	r := &Result{Prefix: l.Prefix(), Start: cursor, End: cursor}
+	if l.InToken {
+		r.Start, r.End = l.Tokens[l.Cursor].Start, l.Tokens[l.Cursor].End
+	}
+
+	var comps map[string]string
+	if inFilter(l) {
+		text := line[:r.Start]
+		if r.Prefix != "" {
+			text += tokenizer.Quote(r.Prefix)
+		}
+		comps, _ = pipe.Complete(text)
+	} else {
+		comps, err = complete(ctx, l.Before(), sources)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	policy := matching.FromContext(ctx)
+	r.Candidates, r.Common = filter(policy, r.Prefix, comps)
+	r.Insert = r.Common
+	if single := singleCandidate(r.Candidates); single != "" {
+		r.Insert = tokenizer.Quote(single)
+		r.Space = true
+	} else if r.Common == r.Prefix && l.InToken {
+		// Leave the token as typed
+		r.Insert = line[r.Start:r.End]
+	}
+	return r, nil
+}
+
+// inFilter returns true if the cursor is after a pipe
+func inFilter(l *tokenizer.Line) bool {
+	for i := 0; i < l.Cursor; i++ {
+		if l.Tokens[i].Pipe {
+			return true
+		}
+	}
+	return false
+}
+
+// complete returns the union of the completions of path from each
+// source it expands in
+func complete(
+	ctx context.Context,
+	path []string,
+	sources []Source,
+) (map[string]string, error) {
+	var firstErr error
+	var comps map[string]string
+	for _, s := range sources {
+		epath, err := s.Expand(ctx, path)
+		if err == nil {
+			var m map[string]string
+			m, err = s.Completion(ctx, epath)
+			if err == nil {
+				if comps == nil {
+					comps = make(map[string]string)
+				}
+				for name, help := range m {
+					if _, ok := comps[name]; !ok {
+						comps[name] = help
+					}
+				}
+				continue
+			}
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if comps == nil {
+		return nil, firstErr
+	}
+	return comps, nil
+}
+
+// filter returns the candidates matching prefix, along with the longest
+// prefix common to those which are not placeholders
+func filter(
+	policy *matching.Policy,
+	prefix string,
+	comps map[string]string,
+) ([]Candidate, string) {
+	var names []string
+	var cands []Candidate
+	for name, help := range comps {
+		c := Candidate{Name: name, Help: help}
+		if c.IsPlaceholder() {
+			cands = append(cands, c)
+		} else {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	names = policy.Filter(prefix, names)
+	for _, name := range names {
+		cands = append(cands, Candidate{Name: name, Help: comps[name]})
+	}
+	sort.Slice(cands, func(i, j int) bool { return cands[i].Name < cands[j].Name })
+
+	if len(names) == 0 {
+		return cands, prefix
+	}
+	common := names[0]
+	for _, name := range names[1:] {
+		for !strings.HasPrefix(name, common) {
+			common = common[:len(common)-1]
+		}
+	}
+	if len(names) > 1 && !strings.HasPrefix(common, prefix) {
+		// The candidates matched other than by prefix
+		return cands, prefix
+	}
+	return cands, common
+}
+
+// singleCandidate returns the only candidate, if it is not a placeholder
+func singleCandidate(cands []Candidate) string {
+	if len(cands) != 1 || cands[0].IsPlaceholder() {
+		return ""
+	}
+	return cands[0].Name
+}
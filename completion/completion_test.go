@@ -0,0 +1,169 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package completion
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/danos/op/alias"
+	"github.com/danos/op/auth"
+	"github.com/danos/op/tmpl"
+	"github.com/danos/op/tmpl/tree"
+)
+
+func buildTree(names ...string) *tree.OpTree {
+	root := tree.NewOpTree("templates", nil)
+	for _, name := range names {
+		n := root
+		for _, elem := range strings.Fields(name) {
+			c, err := n.Child(elem)
+			if err != nil {
+				c = tree.NewOpTree(elem, tmpl.NewOpTmpl("", "Help for "+elem, "", ""))
+				n.AddChild(c)
+			}
+			n = c
+		}
+	}
+	return root
+}
+
+func candidateNames(r *Result) string {
+	var names []string
+	for _, c := range r.Candidates {
+		names = append(names, c.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestComplete(t *testing.T) {
+	src := Tree(buildTree(
+		"show interfaces node.tag brief",
+		"show ip route",
+		"show log",
+		"show login",
+		"shutdown",
+		"set description",
+	), nil)
+
+	tests := []struct {
+		line       string
+		cursor     int
+		candidates string
+		insert     string
+		start, end int
+		space      bool
+	}{
+		{"", 0, "set,show,shutdown", "s", 0, 0, false},
+		{"sh", 2, "show,shutdown", "sh", 0, 2, false},
+		{"show i", 6, "interfaces,ip", "i", 5, 6, false},
+		{"show in", 7, "interfaces", "interfaces", 5, 7, true},
+		{"sho lo", 6, "log,login", "log", 4, 6, false},
+		{"sho int ", 8, "<interfaces>", "", 8, 8, false},
+		{"sho int dp0s3 ", 14, "brief", "brief", 14, 14, true},
+		{"sho int dp0s3 b", 15, "brief", "brief", 14, 15, true},
+		// The cursor in the middle of a token completes what is before
+		// it, replacing the whole token
+		{"show intx log", 7, "interfaces", "interfaces", 5, 9, true},
+		{"show ip route", 6, "interfaces,ip", "ip", 5, 7, false},
+		{"show  log", 5, "interfaces,ip,log,login", "", 5, 5, false},
+		{"show log | m", 12, "match", "match", 11, 12, true},
+		{"show log | ", 11, "begin,count,except,match,no-more,section",
+			"", 11, 11, false},
+		{"show log | match ", 17, "<pattern>", "", 17, 17, false},
+		{"show zzz", 8, "", "zzz", 5, 8, false},
+	}
+
+	for _, test := range tests {
+		r, err := Complete(context.Background(), test.line, test.cursor, src)
+		if err != nil {
+			t.Errorf("%q: unexpected failure: %s\n", test.line, err)
+			continue
+		}
+		if names := candidateNames(r); names != test.candidates {
+			t.Errorf("%q: expected candidates %s, got %s\n",
+				test.line, test.candidates, names)
+		}
+		if r.Insert != test.insert || r.Start != test.start ||
+			r.End != test.end || r.Space != test.space {
+			t.Errorf("%q: expected insert %q at %d-%d (%t), got %q at %d-%d (%t)\n",
+				test.line, test.insert, test.start, test.end, test.space,
+				r.Insert, r.Start, r.End, r.Space)
+		}
+	}
+
+	if _, err := Complete(context.Background(), "bogus ", 6, src); err == nil {
+		t.Errorf("Expected failure completing an invalid path\n")
+	}
+}
+
+func TestCompleteQuoted(t *testing.T) {
+	src := Tree(buildTree("set description node.tag", "set description text"), nil)
+
+	r, err := Complete(context.Background(), `set desc "link to`, 17, src)
+	if err != nil {
+		t.Fatalf("Unexpected failure: %s\n", err)
+	}
+	if r.Prefix != "link to" || candidateNames(r) != "<description>" {
+		t.Errorf("Unexpected completion: %+v\n", r)
+	}
+}
+
+func TestCompleteSources(t *testing.T) {
+	high := Tree(buildTree("show version", "show log"), nil)
+	high.(*treeSource).t.AddChild(tree.NewOpTree("reboot",
+		tmpl.NewOpTmpl("", "First", "", "")))
+	low := Tree(buildTree("show interfaces", "show log", "reboot"), nil)
+
+	r, err := Complete(context.Background(), "show ", 5, high, low)
+	if err != nil {
+		t.Fatalf("Unexpected failure: %s\n", err)
+	}
+	if names := candidateNames(r); names != "interfaces,log,version" {
+		t.Errorf("Unexpected candidates: %s\n", names)
+	}
+
+	r, _ = Complete(context.Background(), "reb", 3, high, low)
+	if len(r.Candidates) != 1 || r.Candidates[0].Help != "First" {
+		t.Errorf("Expected help of first source: %+v\n", r.Candidates)
+	}
+
+	// Paths need only expand in one source
+	r, err = Complete(context.Background(), "sh int ", 7, high, low)
+	if err != nil || len(r.Candidates) != 0 {
+		t.Errorf("Unexpected completion: %+v, %v\n", r, err)
+	}
+}
+
+func TestCompleteAliases(t *testing.T) {
+	src := Tree(buildTree("show interfaces", "show log"), nil)
+	aliases := alias.NewTable()
+	if err := aliases.Add(&alias.Alias{Name: "logs", Expansion: []string{"show", "log"},
+		Help: "Show the log"}, []string{"show"}); err != nil {
+		t.Fatalf("Unexpected failure: %s\n", err)
+	}
+	ctx := alias.NewContext(context.Background(), aliases)
+
+	r, err := Complete(ctx, "l", 1, src)
+	if err != nil || candidateNames(r) != "logs" || !r.Space {
+		t.Errorf("Unexpected completion: %+v, %v\n", r, err)
+	}
+}
+
+func TestCompleteAuthorised(t *testing.T) {
+	denyShutdown := auth.Func(func(path []string) (bool, error) {
+		return len(path) == 0 || path[0] != "shutdown", nil
+	})
+	src := Tree(buildTree("show log", "show login", "shutdown now"), denyShutdown)
+
+	r, err := Complete(context.Background(), "sh", 2, src)
+	if err != nil || candidateNames(r) != "show" || r.Insert != "show" {
+		t.Errorf("Unexpected completion: %+v, %v\n", r, err)
+	}
+	if _, err = Complete(context.Background(), "shutdown ", 9, src); err == nil {
+		t.Errorf("Expected denied command not to be completed\n")
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+// Package docgen generates the operational command reference, in
+// Markdown or as a man page, from a command tree.
+//
+// Each command which runs something is documented with its syntax, help
+// text, description, privilege, the features gating it and whether it
+// is defined in YANG or a template. Hidden commands are left out, along
+// with the commands below them, unless the context is one in which the
+// CLI lists them (see tmpl.WithHidden). Deprecated commands, and those
+// below them, are documented along with the warning the CLI shows when
+// they are run.
+package docgen
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/danos/op/cmdtree"
+	"github.com/danos/op/tmpl"
+)
+
+// Entry is the documentation of a command
+type Entry struct {
+	// Path is the path to the command, starting below the root
+	Path []*cmdtree.Node
+	// Features are those gating the command or any node on its path
+	Features []string
+	// Deprecation is that of the deepest deprecated node on the path,
+	// or nil if there is none
+	Deprecation *tmpl.Deprecation
+}
+
+// Node returns the node of the command
+func (e *Entry) Node() *cmdtree.Node {
+	return e.Path[len(e.Path)-1]
+}
+
+// Syntax returns the elements of the command, with arguments as
+// placeholders
+func (e *Entry) Syntax() []string {
+	return syntax(e.Path)
+}
+
+// Arguments returns the arguments on the path
+func (e *Entry) Arguments() []*cmdtree.Node {
+	var args []*cmdtree.Node
+	for _, n := range e.Path {
+		if n.Arg {
+			args = append(args, n)
+		}
+	}
+	return args
+}
+
+func syntax(path []*cmdtree.Node) []string {
+	elems := make([]string, 0, len(path))
+	for _, n := range path {
+		if n.Arg {
+			elems = append(elems, n.Placeholder())
+		} else {
+			elems = append(elems, n.Name)
+		}
+	}
+	return elems
+}
+
+// Entries returns the entries of the commands of root, in the order the
+// tree is walked
+func Entries(ctx context.Context, root *cmdtree.Node) []*Entry {
+	showHidden := tmpl.ShowHidden(ctx)
+	var entries []*Entry
+	root.Walk(func(path []*cmdtree.Node, n *cmdtree.Node) bool {
+		if n.Hidden && !showHidden {
+			return false
+		}
+		if n.Runnable {
+			entries = append(entries, &Entry{
+				Path:        path,
+				Features:    features(path),
+				Deprecation: deprecation(path),
+			})
+		}
+		return true
+	})
+	return entries
+}
+
+// features returns the features gating the nodes of path, sorted
+func features(path []*cmdtree.Node) []string {
+	seen := make(map[string]bool)
+	var feats []string
+	for _, n := range path {
+		for _, f := range n.Features {
+			if !seen[f] {
+				seen[f] = true
+				feats = append(feats, f)
+			}
+		}
+	}
+	sort.Strings(feats)
+	return feats
+}
+
+// deprecation returns the deprecation of the deepest deprecated node of
+// path, as the CLI reports it
+func deprecation(path []*cmdtree.Node) *tmpl.Deprecation {
+	for i := len(path) - 1; i >= 0; i-- {
+		if n := path[i]; n.Deprecated != "" {
+			return &tmpl.Deprecation{
+				Path:        syntax(path[:i+1]),
+				Warning:     n.Deprecated,
+				Replacement: n.Replacement,
+			}
+		}
+	}
+	return nil
+}
+
+// sourceName returns how the source of a node is documented
+func sourceName(s cmdtree.Source) string {
+	if s == cmdtree.Yang {
+		return "YANG"
+	}
+	return "template"
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// paragraphs splits text into paragraphs at blank lines, joining the
+// lines of each
+func paragraphs(text string) []string {
+	var paras, lines []string
+	flush := func() {
+		if len(lines) > 0 {
+			paras = append(paras, strings.Join(lines, " "))
+			lines = nil
+		}
+	}
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line == "" {
+			flush()
+		} else {
+			lines = append(lines, line)
+		}
+	}
+	flush()
+	return paras
+}
@@ -0,0 +1,155 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package docgen
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/danos/op/cmdtree"
+	"github.com/danos/op/tmpl"
+)
+
+func testTree() *cmdtree.Node {
+	brief := &cmdtree.Node{Name: "brief", Help: "Show a summary", Runnable: true,
+		Source: cmdtree.Yang}
+	ifname := &cmdtree.Node{Name: "interfaces", Arg: true, Help: "Interface name",
+		Runnable: true, Source: cmdtree.Yang, Children: []*cmdtree.Node{brief}}
+	interfaces := &cmdtree.Node{Name: "interfaces", Help: "Show interfaces",
+		Description: "Show the state\nof interfaces.\n\nSee also 'show ip'.",
+		Runnable:    true, Features: []string{"vyatta-if:dataplane"},
+		Source: cmdtree.Yang, Children: []*cmdtree.Node{ifname}}
+	debug := &cmdtree.Node{Name: "debug", Help: "Show debugging", Runnable: true,
+		Hidden: true, Children: []*cmdtree.Node{
+			{Name: "all", Help: "Show all debugging", Runnable: true},
+		}}
+	tail := &cmdtree.Node{Name: "tail", Help: "Follow the log", Runnable: true,
+		Privileged: true, Features: []string{"vyatta-log:tail"}}
+	log := &cmdtree.Node{Name: "log", Help: "Show the log", Runnable: true,
+		Privileged: true, Deprecated: "Moved", Replacement: "show logging",
+		Children: []*cmdtree.Node{tail}}
+	root := &cmdtree.Node{Children: []*cmdtree.Node{
+		{Name: "show", Help: "Show system information",
+			Children: []*cmdtree.Node{debug, interfaces, log}},
+		{Name: "reset", Help: "Reset a counter", Children: []*cmdtree.Node{
+			{Name: "counter", Arg: true, Values: []string{"ip", "ipv6"},
+				Runnable: true, Privileged: true},
+		}},
+	}}
+	root.Sort()
+	return root
+}
+
+func syntaxes(entries []*Entry) string {
+	var out []string
+	for _, e := range entries {
+		out = append(out, strings.Join(e.Syntax(), " "))
+	}
+	return strings.Join(out, ",")
+}
+
+func TestEntries(t *testing.T) {
+	root := testTree()
+
+	entries := Entries(context.Background(), root)
+	expected := "reset <counter>,show interfaces,show interfaces <interfaces>," +
+		"show interfaces <interfaces> brief,show log,show log tail"
+	if got := syntaxes(entries); got != expected {
+		t.Errorf("Unexpected entries:\n%s\n", got)
+	}
+
+	entries = Entries(tmpl.WithHidden(context.Background()), root)
+	expected = "reset <counter>,show debug,show debug all,show interfaces," +
+		"show interfaces <interfaces>,show interfaces <interfaces> brief," +
+		"show log,show log tail"
+	if got := syntaxes(entries); got != expected {
+		t.Errorf("Unexpected entries with hidden commands:\n%s\n", got)
+	}
+
+	for _, e := range entries {
+		switch strings.Join(e.Syntax(), " ") {
+		case "show interfaces <interfaces> brief":
+			if strings.Join(e.Features, ",") != "vyatta-if:dataplane" {
+				t.Errorf("Unexpected features: %v\n", e.Features)
+			}
+		case "show log tail":
+			d := e.Deprecation
+			if d == nil || d.String() !=
+				"Warning: 'show log' is deprecated: Moved\nUse 'show logging' instead" {
+				t.Errorf("Unexpected deprecation: %v\n", d)
+			}
+		case "show interfaces":
+			if e.Deprecation != nil {
+				t.Errorf("Unexpected deprecation: %v\n", e.Deprecation)
+			}
+		}
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteMarkdown(context.Background(), &buf, "Operational commands",
+		testTree()); err != nil {
+		t.Fatalf("Unexpected failure: %s\n", err)
+	}
+	doc := buf.String()
+	for _, expected := range []string{
+		"# Operational commands\n",
+		"\n## `reset <counter>`\n\nArguments:\n\n- `<counter>` (one of `ip`, `ipv6`)\n" +
+			"\n- Privileged: yes\n- Source: template\n",
+		"\n## `show interfaces`\n\nShow interfaces\n\nShow the state of interfaces.\n" +
+			"\nSee also 'show ip'.\n\n- Privileged: no\n" +
+			"- Features: `vyatta-if:dataplane`\n- Source: YANG\n",
+		"- `<interfaces>`: Interface name\n",
+		"\n## `show log tail`\n\nFollow the log\n\n" +
+			"> Warning: 'show log' is deprecated: Moved\n> Use 'show logging' instead\n",
+		"- Features: `vyatta-log:tail`\n",
+	} {
+		if !strings.Contains(doc, expected) {
+			t.Errorf("Expected %q in document:\n%s\n", expected, doc)
+		}
+	}
+	if strings.Contains(doc, "debug") || strings.Contains(doc, "Hidden") {
+		t.Errorf("Hidden command documented:\n%s\n", doc)
+	}
+}
+
+func TestWriteMan(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := tmpl.WithHidden(context.Background())
+	if err := WriteMan(ctx, &buf, "vyatta-op", testTree()); err != nil {
+		t.Fatalf("Unexpected failure: %s\n", err)
+	}
+	doc := buf.String()
+	for _, expected := range []string{
+		".TH \"VYATTA-OP\" 8 \"\" \"\" \"Operational Commands\"\n",
+		".SH NAME\nvyatta\\-op \\- operational command reference\n",
+		".SS \\fBshow\\fR \\fBinterfaces\\fR \\fI<interfaces>\\fR \\fBbrief\\fR\n",
+		".TP\n\\fI<counter>\\fR\n(one of ip, ipv6)\n",
+		".TP\n.B Features\nvyatta\\-if:dataplane\n",
+		".SS \\fBshow\\fR \\fBdebug\\fR\n.PP\nShow debugging\n",
+		".TP\n.B Hidden\nyes\n",
+		"Use 'show logging' instead\n.br\n",
+	} {
+		if !strings.Contains(doc, expected) {
+			t.Errorf("Expected %q in document:\n%s\n", expected, doc)
+		}
+	}
+}
+
+func TestRoff(t *testing.T) {
+	for in, expected := range map[string]string{
+		`.hidden`:    `\&.hidden`,
+		`'quoted'`:   `\&'quoted'`,
+		`a\b-c`:      `a\eb\-c`,
+		"plain text": "plain text",
+	} {
+		if got := roff(in); got != expected {
+			t.Errorf("%q: expected %q, got %q\n", in, expected, got)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package docgen
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/danos/op/cmdtree"
+)
+
+var roffEscaper = strings.NewReplacer(`\`, `\e`, "-", `\-`)
+
+// roff escapes text for a line of a man page
+func roff(text string) string {
+	text = roffEscaper.Replace(text)
+	if strings.HasPrefix(text, ".") || strings.HasPrefix(text, "'") {
+		text = `\&` + text
+	}
+	return text
+}
+
+// WriteMan writes the reference of the commands of root as a section 8
+// man page with the given name
+func WriteMan(
+	ctx context.Context,
+	w io.Writer,
+	name string,
+	root *cmdtree.Node,
+) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, ".TH %q 8 \"\" \"\" \"Operational Commands\"\n",
+		strings.ToUpper(name))
+	fmt.Fprintf(&b, ".SH NAME\n%s \\- operational command reference\n", roff(name))
+	b.WriteString(".SH COMMANDS\n")
+	for _, e := range Entries(ctx, root) {
+		writeManEntry(&b, e)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// manSyntax returns the syntax of e with keywords in bold and arguments
+// in italics
+func manSyntax(e *Entry) string {
+	var elems []string
+	for _, n := range e.Path {
+		if n.Arg {
+			elems = append(elems, `\fI`+roff(n.Placeholder())+`\fR`)
+		} else {
+			elems = append(elems, `\fB`+roff(n.Name)+`\fR`)
+		}
+	}
+	return strings.Join(elems, " ")
+}
+
+func writeManEntry(b *strings.Builder, e *Entry) {
+	n := e.Node()
+	fmt.Fprintf(b, ".SS %s\n", manSyntax(e))
+	for _, p := range paragraphs(n.Help) {
+		fmt.Fprintf(b, ".PP\n%s\n", roff(p))
+	}
+	for _, p := range paragraphs(n.Description) {
+		fmt.Fprintf(b, ".PP\n%s\n", roff(p))
+	}
+	if d := e.Deprecation; d != nil {
+		b.WriteString(".PP\n")
+		for _, line := range strings.Split(d.String(), "\n") {
+			fmt.Fprintf(b, "%s\n.br\n", roff(line))
+		}
+	}
+
+	for _, a := range e.Arguments() {
+		fmt.Fprintf(b, ".TP\n\\fI%s\\fR\n", roff(a.Placeholder()))
+		text := strings.Join(paragraphs(a.Help), " ")
+		if len(a.Values) > 0 {
+			if text != "" {
+				text += " "
+			}
+			text += "(one of " + strings.Join(a.Values, ", ") + ")"
+		}
+		fmt.Fprintf(b, "%s\n", roff(text))
+	}
+
+	fmt.Fprintf(b, ".TP\n.B Privileged\n%s\n", yesNo(n.Privileged))
+	if len(e.Features) > 0 {
+		fmt.Fprintf(b, ".TP\n.B Features\n%s\n", roff(strings.Join(e.Features, ", ")))
+	}
+	fmt.Fprintf(b, ".TP\n.B Source\n%s\n", sourceName(n.Source))
+	if n.Hidden {
+		b.WriteString(".TP\n.B Hidden\nyes\n")
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package docgen
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/danos/op/cmdtree"
+)
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+	"<", `\<`, ">", `\>`, "#", `\#`, "|", `\|`,
+)
+
+// WriteMarkdown writes the reference of the commands of root as a
+// Markdown document with the given title
+func WriteMarkdown(
+	ctx context.Context,
+	w io.Writer,
+	title string,
+	root *cmdtree.Node,
+) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n", markdownEscaper.Replace(title))
+	for _, e := range Entries(ctx, root) {
+		writeMarkdownEntry(&b, e)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeMarkdownEntry(b *strings.Builder, e *Entry) {
+	n := e.Node()
+	fmt.Fprintf(b, "\n## `%s`\n", strings.Join(e.Syntax(), " "))
+	for _, p := range paragraphs(n.Help) {
+		fmt.Fprintf(b, "\n%s\n", markdownEscaper.Replace(p))
+	}
+	for _, p := range paragraphs(n.Description) {
+		fmt.Fprintf(b, "\n%s\n", markdownEscaper.Replace(p))
+	}
+	if d := e.Deprecation; d != nil {
+		b.WriteString("\n")
+		for _, line := range strings.Split(d.String(), "\n") {
+			fmt.Fprintf(b, "> %s\n", markdownEscaper.Replace(line))
+		}
+	}
+
+	if args := e.Arguments(); len(args) > 0 {
+		b.WriteString("\nArguments:\n\n")
+		for _, a := range args {
+			fmt.Fprintf(b, "- `%s`", a.Placeholder())
+			if help := strings.Join(paragraphs(a.Help), " "); help != "" {
+				fmt.Fprintf(b, ": %s", markdownEscaper.Replace(help))
+			}
+			if len(a.Values) > 0 {
+				fmt.Fprintf(b, " (one of `%s`)", strings.Join(a.Values, "`, `"))
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	fmt.Fprintf(b, "\n- Privileged: %s\n", yesNo(n.Privileged))
+	if len(e.Features) > 0 {
+		fmt.Fprintf(b, "- Features: `%s`\n", strings.Join(e.Features, "`, `"))
+	}
+	fmt.Fprintf(b, "- Source: %s\n", sourceName(n.Source))
+	if n.Hidden {
+		b.WriteString("- Hidden: yes\n")
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package exec
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/danos/op/tmpl"
+)
+
+// ErrNotConfirmed is returned for a command requiring confirmation which
+// was declined, or could not be asked for
+var ErrNotConfirmed = errors.New("Command not confirmed")
+
+// ConfirmFunc asks for confirmation with prompt, returning the answer.
+// def is the answer to assume if none is given.
+type ConfirmFunc func(prompt string, def bool) (bool, error)
+
+// Prompt returns a ConfirmFunc which writes the prompt to out and reads
+// the answer from in, repeating the prompt until a valid answer or an
+// empty line, which gives the default, is read. The end of in declines.
+func Prompt(in io.Reader, out io.Writer) ConfirmFunc {
+	r := bufio.NewReader(in)
+	return func(prompt string, def bool) (bool, error) {
+		choices := "[y/N]"
+		if def {
+			choices = "[Y/n]"
+		}
+		for {
+			fmt.Fprintf(out, "%s %s ", prompt, choices)
+			line, err := r.ReadString('\n')
+			if line == "" && err == io.EOF {
+				fmt.Fprintln(out)
+				return false, nil
+			}
+			if err != nil && err != io.EOF {
+				return false, err
+			}
+			if len(line) > 0 && line[len(line)-1] == '\n' {
+				line = line[:len(line)-1]
+			}
+			if line == "" {
+				return def, nil
+			}
+			if answer, err := tmpl.ParseAnswer(line); err == nil {
+				return answer, nil
+			}
+		}
+	}
+}
+
+// confirm asks for confirmation if the template requires it and the
+// command has not been confirmed in advance
+func (c *Cmd) confirm() error {
+	prompt := c.Invocation.Template.Confirm()
+	if prompt == "" || c.Confirmed {
+		return nil
+	}
+	if c.Confirm == nil {
+		return ErrNotConfirmed
+	}
+	ok, err := c.Confirm(prompt, c.Invocation.Template.ConfirmDefault())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotConfirmed
+	}
+	return nil
+}
@@ -0,0 +1,370 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+/*
+Package exec runs resolved operational mode commands.
+
+The run snippet of a command's template is run by /bin/sh with the
+elements of the command's path as its positional parameters, so that
+$1 is the first element of the path. Commands whose template is not
+marked priv are run as the invoking user.
+*/
+package exec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	osexec "os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/danos/op/accounting"
+	"github.com/danos/op/auth"
+	"github.com/danos/op/tmpl"
+)
+
+// Shell is the shell run snippets are run by
+const Shell = "/bin/sh"
+
+// DefaultKillGrace is how long a cancelled command is given to exit
+// after SIGTERM before it is sent SIGKILL
+const DefaultKillGrace = 2 * time.Second
+
+// ErrNoRun is returned for a template without a run snippet
+var ErrNoRun = errors.New("Command has no run snippet")
+
+// ErrNoCredential is returned for an unprivileged command without a
+// Credential, which would otherwise be run as root
+var ErrNoCredential = errors.New("No credential to run unprivileged command")
+
+// geteuid is replaced by tests
+var geteuid = os.Geteuid
+
+// ErrOutputLimit is returned when a command is stopped for producing
+// more output than its template allows
+var ErrOutputLimit = errors.New("Command output limit exceeded")
+
+// Cmd is a resolved command to be run
+type Cmd struct {
+	Invocation *tmpl.Invocation
+
+	// Credential identifies the invoking user. Unprivileged commands
+	// are run with it; if nil they are run as the current process,
+	// unless that is root, when they are refused with ErrNoCredential.
+	Credential *syscall.Credential
+
+	// Env is the environment, os.Environ() if nil. OPC_ARGS is added
+	// when the template passes opc arguments.
+	Env []string
+	Dir string
+
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	KillGrace time.Duration
+
+	// Substitute runs the snippet with argument references replaced
+	// by their quoted values, as by tmpl.Substitute, rather than
+	// leaving the shell to expand them
+	Substitute bool
+
+	// Accounter, if set, is given a record of each run on behalf of
+	// User
+	Accounter accounting.Accounter
+	User      auth.User
+
+	// Confirm asks for confirmation of a command whose template
+	// requires it. Non-interactive callers leave it nil and set
+	// Confirmed to run such commands, which are otherwise refused.
+	Confirm   ConfirmFunc
+	Confirmed bool
+}
+
+// New returns a Cmd for inv, streaming to the process's own stdout
+// and stderr
+func New(inv *tmpl.Invocation) *Cmd {
+	return &Cmd{
+		Invocation: inv,
+		Stdout:     os.Stdout,
+		Stderr:     os.Stderr,
+		KillGrace:  DefaultKillGrace,
+	}
+}
+
+// OpcArgs returns the value of OPC_ARGS for path: each element quoted
+// for the shell, separated by spaces, so that 'eval set -- $OPC_ARGS'
+// recovers them.
+func OpcArgs(path []string) string {
+	quoted := make([]string, len(path))
+	for i, p := range path {
+		quoted[i] = tmpl.ShellQuote(p)
+	}
+	return strings.Join(quoted, " ")
+}
+
+func (c *Cmd) environ() []string {
+	env := c.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	if c.Invocation.Template.PassOpcArgs() {
+		env = append(env[:len(env):len(env)],
+			"OPC_ARGS="+OpcArgs(c.Invocation.Path))
+	}
+	return env
+}
+
+// isTerminal returns true if r is a terminal
+func isTerminal(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	if !ok {
+		return false
+	}
+	var t syscall.Termios
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(),
+		syscall.TCGETS, uintptr(unsafe.Pointer(&t)))
+	return errno == 0
+}
+
+// sysProcAttr returns the attributes of the command's process. It is
+// put in a process group of its own, so that it can be stopped along
+// with all it runs, unless its stdin is a terminal: the terminal would
+// then stop it for reading from the background.
+func (c *Cmd) sysProcAttr() (*syscall.SysProcAttr, error) {
+	attr := &syscall.SysProcAttr{Setpgid: !isTerminal(c.Stdin)}
+	switch {
+	case c.Invocation.Template.Priv():
+	case c.Credential != nil:
+		attr.Credential = c.Credential
+	case geteuid() == 0:
+		return nil, ErrNoCredential
+	}
+	return attr, nil
+}
+
+func (c *Cmd) command() (*osexec.Cmd, error) {
+	attr, err := c.sysProcAttr()
+	if err != nil {
+		return nil, err
+	}
+	run := c.Invocation.Template.Run()
+	if strings.TrimSpace(run) == "" {
+		return nil, ErrNoRun
+	}
+	if c.Substitute {
+		run = c.Invocation.Run()
+	}
+	run = ulimits(c.Invocation.Template) + run
+	args := append([]string{"-c", run, Shell}, c.Invocation.Path...)
+	cmd := osexec.Command(Shell, args...)
+	cmd.Env = c.environ()
+	cmd.Dir = c.Dir
+	cmd.Stdin = c.Stdin
+	cmd.Stdout = c.Stdout
+	cmd.Stderr = c.Stderr
+	cmd.SysProcAttr = attr
+	// A process left running with the command's output, as one which
+	// escaped being stopped, must not hold up Wait
+	cmd.WaitDelay = c.KillGrace
+	if cmd.WaitDelay <= 0 {
+		cmd.WaitDelay = DefaultKillGrace
+	}
+	return cmd, nil
+}
+
+// exitStatus returns the shell style exit status for the result of
+// waiting for a command: 128 plus the signal number if it was killed.
+func exitStatus(err error) (int, error) {
+	if err == nil {
+		return 0, nil
+	}
+	if errors.Is(err, osexec.ErrWaitDelay) {
+		// The command succeeded, but something it started kept its
+		// output open
+		return 0, nil
+	}
+	var exitErr *osexec.ExitError
+	if !errors.As(err, &exitErr) {
+		return -1, err
+	}
+	if ws, ok := exitErr.Sys().(syscall.WaitStatus); ok {
+		if ws.Signaled() {
+			return 128 + int(ws.Signal()), nil
+		}
+		return ws.ExitStatus(), nil
+	}
+	return exitErr.ExitCode(), nil
+}
+
+// Run runs the command, returning its exit status once it exits. A
+// non-zero exit status is not an error. If ctx is done first, or the
+// command exceeds its template's timeout or output limit, the
+// command's process group is sent SIGTERM, then SIGKILL after the kill
+// grace period. ctx's error, or ErrOutputLimit, is then returned. A
+// command reading from a terminal has no group of its own, so only its
+// shell is signalled. Output left open by a process outliving the
+// command is closed after the kill grace period.
+// A command requiring confirmation is not run unless it is confirmed.
+func (c *Cmd) Run(ctx context.Context) (int, error) {
+	if c.Invocation == nil || c.Invocation.Template == nil {
+		return -1, ErrNoRun
+	}
+	if err := c.confirm(); err != nil {
+		return -1, err
+	}
+	if c.Accounter == nil {
+		return c.run(ctx)
+	}
+	var status int
+	rec := accounting.NewRecord(c.User, c.Invocation)
+	err := accounting.Run(ctx, c.Accounter, rec, func() (int, error) {
+		var err error
+		status, err = c.run(ctx)
+		return status, err
+	})
+	return status, err
+}
+
+// ulimits returns shell commands setting the rlimits declared by t.
+// Setting them in the shell applies them to the run snippet and all
+// it runs, and as both soft and hard limits they cannot be raised.
+func ulimits(t *tmpl.OpTmpl) string {
+	var cmds []string
+	if cpu := t.CPULimit(); cpu > 0 {
+		secs := (cpu + time.Second - 1) / time.Second
+		cmds = append(cmds, fmt.Sprintf("ulimit -t %d", secs))
+	}
+	if mem := t.MemLimit(); mem > 0 {
+		cmds = append(cmds, fmt.Sprintf("ulimit -v %d", (mem+1023)/1024))
+	}
+	if len(cmds) == 0 {
+		return ""
+	}
+	return strings.Join(cmds, " && ") + " || exit 126\n"
+}
+
+// limitWriter passes on writes until the output shared by all the
+// writers of a command reaches its limit, then stops the command
+type limitWriter struct {
+	w      io.Writer
+	shared *outputLimit
+}
+
+type outputLimit struct {
+	mu       sync.Mutex
+	left     uint64
+	exceeded bool
+	stop     func()
+}
+
+func (l *limitWriter) Write(p []byte) (int, error) {
+	o := l.shared
+	o.mu.Lock()
+	n := len(p)
+	if uint64(n) > o.left {
+		n = int(o.left)
+		if !o.exceeded {
+			o.exceeded = true
+			o.stop()
+		}
+	}
+	o.left -= uint64(n)
+	o.mu.Unlock()
+
+	if n > 0 {
+		if _, err := l.w.Write(p[:n]); err != nil {
+			return 0, err
+		}
+	}
+	// Report the whole write so the command sees no error while it
+	// is being stopped
+	return len(p), nil
+}
+
+// limitOutput wraps the command's output writers to enforce the output
+// limit, returning whether it was exceeded
+func (c *Cmd) limitOutput(cmd *osexec.Cmd, stop func()) func() bool {
+	max := c.Invocation.Template.OutputLimit()
+	if max == 0 {
+		return func() bool { return false }
+	}
+	o := &outputLimit{left: max, stop: stop}
+	if cmd.Stdout != nil {
+		cmd.Stdout = &limitWriter{w: cmd.Stdout, shared: o}
+	}
+	if cmd.Stderr != nil {
+		cmd.Stderr = &limitWriter{w: cmd.Stderr, shared: o}
+	}
+	return func() bool {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		return o.exceeded
+	}
+}
+
+// run runs the command enforcing the limits declared by its template:
+// the timeout as a context deadline, the output limit by stopping the
+// command once it is exceeded and the others as rlimits.
+func (c *Cmd) run(ctx context.Context) (int, error) {
+	cmd, err := c.command()
+	if err != nil {
+		return -1, err
+	}
+	if timeout := c.Invocation.Template.Timeout(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+	exceeded := c.limitOutput(cmd, stop)
+
+	status, err := c.wait(ctx, cmd)
+	if exceeded() {
+		err = ErrOutputLimit
+	}
+	return status, err
+}
+
+// wait starts cmd and waits for it to exit, stopping it if ctx is done
+// first
+func (c *Cmd) wait(ctx context.Context, cmd *osexec.Cmd) (int, error) {
+	if err := cmd.Start(); err != nil {
+		return -1, err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	select {
+	case err := <-done:
+		return exitStatus(err)
+	case <-ctx.Done():
+	}
+
+	pid := cmd.Process.Pid
+	if cmd.SysProcAttr.Setpgid {
+		pid = -pid
+	}
+	syscall.Kill(pid, syscall.SIGTERM)
+	grace := time.NewTimer(c.KillGrace)
+	defer grace.Stop()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-grace.C:
+		syscall.Kill(pid, syscall.SIGKILL)
+		err = <-done
+	}
+	status, _ := exitStatus(err)
+	return status, ctx.Err()
+}
@@ -0,0 +1,330 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package exec
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/danos/op/accounting"
+	"github.com/danos/op/tmpl"
+)
+
+func testCmd(run string, path ...string) (*Cmd, *bytes.Buffer, *bytes.Buffer) {
+	inv := &tmpl.Invocation{
+		Path:     path,
+		Template: tmpl.NewOpTmpl("", "", "", run),
+	}
+	var stdout, stderr bytes.Buffer
+	c := New(inv)
+	c.Env = []string{"PATH=/usr/bin:/bin"}
+	c.Credential = &syscall.Credential{
+		Uid:         uint32(os.Getuid()),
+		Gid:         uint32(os.Getgid()),
+		NoSetGroups: true,
+	}
+	c.Stdout, c.Stderr = &stdout, &stderr
+	return c, &stdout, &stderr
+}
+
+func TestRunOutput(t *testing.T) {
+	c, stdout, stderr := testCmd(`echo "$1 $3"; echo oops >&2`,
+		"show", "user", "fred")
+
+	status, err := c.Run(context.Background())
+	if err != nil || status != 0 {
+		t.Fatalf("Unexpected result: %d, %v\n", status, err)
+	}
+	if stdout.String() != "show fred\n" || stderr.String() != "oops\n" {
+		t.Errorf("Unexpected output: %q, %q\n", stdout, stderr)
+	}
+}
+
+func TestRunExitStatus(t *testing.T) {
+	c, _, _ := testCmd("exit 3", "fail")
+	if status, err := c.Run(context.Background()); err != nil || status != 3 {
+		t.Errorf("Unexpected result: %d, %v\n", status, err)
+	}
+
+	c, _, _ = testCmd("", "empty")
+	if _, err := c.Run(context.Background()); err != ErrNoRun {
+		t.Errorf("Expected no run snippet, got %v\n", err)
+	}
+
+	// Checked before asking for confirmation
+	for _, inv := range []*tmpl.Invocation{nil, {Path: []string{"none"}}} {
+		c = New(inv)
+		c.Confirm = func(string, bool) (bool, error) {
+			t.Fatalf("Unexpected confirmation\n")
+			return false, nil
+		}
+		if _, err := c.Run(context.Background()); err != ErrNoRun {
+			t.Errorf("Expected no run snippet for %v, got %v\n", inv, err)
+		}
+	}
+}
+
+func TestRunOpcArgs(t *testing.T) {
+	c, stdout, _ := testCmd(`eval set -- $OPC_ARGS; echo "$#:$2"`,
+		"show", "it's here", "$HOME")
+	c.Invocation.Template.SetPassOpcArgs(true)
+
+	if _, err := c.Run(context.Background()); err != nil {
+		t.Fatalf("Unexpected failure: %s\n", err)
+	}
+	if stdout.String() != "3:it's here\n" {
+		t.Errorf("Unexpected output: %q\n", stdout)
+	}
+
+	c, stdout, _ = testCmd(`echo "${OPC_ARGS-unset}"`, "show")
+	c.Run(context.Background())
+	if stdout.String() != "unset\n" {
+		t.Errorf("OPC_ARGS set without pass-opc-args: %q\n", stdout)
+	}
+}
+
+func TestRunCancel(t *testing.T) {
+	c, _, _ := testCmd("sleep 10 & wait", "sleep")
+	c.KillGrace = 100 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	status, err := c.Run(ctx)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Expected deadline exceeded, got %v\n", err)
+	}
+	if status <= 128 {
+		t.Errorf("Expected status for a signal, got %d\n", status)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("Command was not stopped promptly\n")
+	}
+}
+
+func TestRunEscapedOutput(t *testing.T) {
+	// The background sleep keeps stdout open after the shell exits
+	c, stdout, _ := testCmd("sleep 10 & echo started", "sleep")
+	c.KillGrace = 100 * time.Millisecond
+
+	start := time.Now()
+	status, err := c.Run(context.Background())
+	if status != 0 || err != nil {
+		t.Fatalf("Unexpected result: %d, %v\n", status, err)
+	}
+	if stdout.String() != "started\n" {
+		t.Errorf("Unexpected output: %s\n", stdout.String())
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("Wait held up by escaped process\n")
+	}
+}
+
+func TestProcessGroup(t *testing.T) {
+	c, _, _ := testCmd("true", "test")
+	if attr, err := c.sysProcAttr(); err != nil || !attr.Setpgid {
+		t.Errorf("Command without a terminal not in its own group\n")
+	}
+
+	null, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatalf("Unable to open %s: %s\n", os.DevNull, err)
+	}
+	defer null.Close()
+	c.Stdin = null
+	if attr, err := c.sysProcAttr(); err != nil || !attr.Setpgid {
+		t.Errorf("Command reading a file not in its own group\n")
+	}
+}
+
+func TestPrivilege(t *testing.T) {
+	c, _, _ := testCmd("true", "test")
+	cred := &syscall.Credential{Uid: 1000, Gid: 1000}
+	c.Credential = cred
+
+	if attr, err := c.sysProcAttr(); err != nil || attr.Credential != cred {
+		t.Errorf("Unprivileged command not run as invoking user\n")
+	}
+	c.Invocation.Template.SetPriv(true)
+	if attr, err := c.sysProcAttr(); err != nil || attr.Credential != nil {
+		t.Errorf("Privileged command run as invoking user\n")
+	}
+}
+
+func TestNoCredential(t *testing.T) {
+	defer func(f func() int) { geteuid = f }(geteuid)
+	c, stdout, _ := testCmd("echo run", "test")
+	c.Credential = nil
+
+	geteuid = func() int { return 0 }
+	if _, err := c.Run(context.Background()); err != ErrNoCredential {
+		t.Errorf("Expected unprivileged command refused as root, got %v\n", err)
+	}
+	c.Invocation.Template.SetPriv(true)
+	if attr, err := c.sysProcAttr(); err != nil || attr.Credential != nil {
+		t.Errorf("Unexpected privileged attributes: %v, %v\n", attr, err)
+	}
+
+	c.Invocation.Template.SetPriv(false)
+	geteuid = func() int { return 1000 }
+	if attr, err := c.sysProcAttr(); err != nil || attr.Credential != nil {
+		t.Errorf("Expected command run as the current user: %v, %v\n", attr, err)
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("Refused command was run: %q\n", stdout)
+	}
+}
+
+func TestRunAccounting(t *testing.T) {
+	var rec *accounting.Record
+	c, _, _ := testCmd("exit 4", "show", "version")
+	c.Accounter = accounting.Func(func(ctx context.Context, r *accounting.Record) error {
+		rec = r
+		return nil
+	})
+	c.User.Name = "fred"
+
+	if status, _ := c.Run(context.Background()); status != 4 {
+		t.Fatalf("Unexpected status: %d\n", status)
+	}
+	if rec == nil || rec.ExitStatus != 4 || rec.User.Name != "fred" ||
+		!strings.HasPrefix(rec.Path, "show version") {
+		t.Errorf("Unexpected record: %+v\n", rec)
+	}
+}
+
+func TestRunSubstitute(t *testing.T) {
+	c, stdout, _ := testCmd(`echo $2; shift; echo $1`, "show", "*", "log")
+	c.Substitute = true
+	c.Dir = "/"
+
+	if _, err := c.Run(context.Background()); err != nil {
+		t.Fatalf("Unexpected failure: %s\n", err)
+	}
+	if stdout.String() != "*\nshow\n" {
+		t.Errorf("Unexpected output: %q\n", stdout)
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	c, _, _ := testCmd("sleep 10", "sleep")
+	c.Invocation.Template.SetTimeout(100 * time.Millisecond)
+	c.KillGrace = 100 * time.Millisecond
+
+	start := time.Now()
+	status, err := c.Run(context.Background())
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Expected deadline exceeded, got %d, %v\n", status, err)
+	}
+	if status != 128+int(syscall.SIGTERM) {
+		t.Errorf("Unexpected status: %d\n", status)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("Command not stopped at its timeout\n")
+	}
+}
+
+func TestRunOutputLimit(t *testing.T) {
+	c, stdout, stderr := testCmd(
+		"echo 12345; echo 67890 >&2; while :; do echo more; done", "flood")
+	c.Invocation.Template.SetOutputLimit(8)
+	c.KillGrace = 100 * time.Millisecond
+
+	if _, err := c.Run(context.Background()); err != ErrOutputLimit {
+		t.Fatalf("Expected output limit exceeded, got %v\n", err)
+	}
+	if stdout.Len()+stderr.Len() != 8 {
+		t.Errorf("Unexpected output: %q, %q\n", stdout, stderr)
+	}
+	if !strings.HasPrefix(stdout.String(), "12345\n") {
+		t.Errorf("Unexpected output: %q\n", stdout)
+	}
+
+	c, stdout, _ = testCmd("echo 12345", "small")
+	c.Invocation.Template.SetOutputLimit(8)
+	if _, err := c.Run(context.Background()); err != nil {
+		t.Fatalf("Unexpected failure: %s\n", err)
+	}
+	if stdout.String() != "12345\n" {
+		t.Errorf("Unexpected output: %q\n", stdout)
+	}
+}
+
+func TestRunRlimits(t *testing.T) {
+	c, stdout, _ := testCmd(`ulimit -t; ulimit -v`, "limits")
+	c.Invocation.Template.SetCPULimit(1500 * time.Millisecond)
+	c.Invocation.Template.SetMemLimit(64 << 20)
+
+	if _, err := c.Run(context.Background()); err != nil {
+		t.Fatalf("Unexpected failure: %s\n", err)
+	}
+	if stdout.String() != "2\n65536\n" {
+		t.Errorf("Unexpected limits: %q\n", stdout)
+	}
+}
+
+func TestRunConfirm(t *testing.T) {
+	c, stdout, _ := testCmd("echo rebooting", "reboot")
+	c.Invocation.Template.SetConfirm("Reboot now?")
+	if _, err := c.Run(context.Background()); err != ErrNotConfirmed {
+		t.Fatalf("Expected unconfirmed failure, got %v\n", err)
+	}
+
+	var prompts []string
+	answer := false
+	c.Confirm = func(prompt string, def bool) (bool, error) {
+		prompts = append(prompts, prompt)
+		return answer, nil
+	}
+	if _, err := c.Run(context.Background()); err != ErrNotConfirmed {
+		t.Fatalf("Expected declined failure, got %v\n", err)
+	}
+	answer = true
+	if _, err := c.Run(context.Background()); err != nil {
+		t.Fatalf("Unexpected failure: %s\n", err)
+	}
+	if len(prompts) != 2 || prompts[0] != "Reboot now?" {
+		t.Errorf("Unexpected prompts: %q\n", prompts)
+	}
+
+	c.Confirm, c.Confirmed = nil, true
+	if _, err := c.Run(context.Background()); err != nil {
+		t.Fatalf("Unexpected failure when pre-confirmed: %s\n", err)
+	}
+	if stdout.String() != "rebooting\nrebooting\n" {
+		t.Errorf("Unexpected output: %q\n", stdout)
+	}
+}
+
+func TestPrompt(t *testing.T) {
+	for _, tc := range []struct {
+		in     string
+		def    bool
+		answer bool
+		out    string
+	}{
+		{"y\n", false, true, "Sure? [y/N] "},
+		{"\n", false, false, "Sure? [y/N] "},
+		{"\n", true, true, "Sure? [Y/n] "},
+		{"maybe\nNo\n", true, false, "Sure? [Y/n] Sure? [Y/n] "},
+		{"yes", false, true, "Sure? [y/N] "},
+		{"", true, false, "Sure? [Y/n] \n"},
+	} {
+		var out bytes.Buffer
+		answer, err := Prompt(strings.NewReader(tc.in), &out)("Sure?", tc.def)
+		if err != nil || answer != tc.answer {
+			t.Errorf("%q: unexpected answer %t, %v\n", tc.in, answer, err)
+		}
+		if out.String() != tc.out {
+			t.Errorf("%q: unexpected prompt %q\n", tc.in, out.String())
+		}
+	}
+}
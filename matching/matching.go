@@ -0,0 +1,248 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+/*
+Package matching defines how a typed path element is matched against
+the names of the commands available at that position.
+
+By default an element matches the names it is a prefix of. A Policy
+may instead match case-insensitively, or match names containing the
+element's characters in order, so that "intf" matches "interfaces".
+Under the latter an element may also span several positions, so that
+"shintf" is taken as "show interfaces". A Policy may also carry a
+table of aliases, such as those used by other vendors, each naming the
+command it stands for.
+
+A Policy is opted in to by carrying it in the context of a request.
+*/
+package matching
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// Mode selects how elements are compared with names
+type Mode int
+
+const (
+	// Prefix matches names the element is a prefix of
+	Prefix Mode = iota
+	// CaseInsensitive is Prefix ignoring case
+	CaseInsensitive
+	// Subsequence matches names containing the characters of the
+	// element in order, ignoring case. Names matched by prefix are
+	// preferred, so that subsequence matches never make an otherwise
+	// unique prefix ambiguous. An element matching nothing may be
+	// divided between successive positions by Split.
+	Subsequence
+)
+
+func (m Mode) String() string {
+	switch m {
+	case Prefix:
+		return "prefix"
+	case CaseInsensitive:
+		return "case-insensitive"
+	case Subsequence:
+		return "subsequence"
+	}
+	return "unknown"
+}
+
+// Policy is a way of matching path elements. The nil Policy matches by
+// case-sensitive prefix without aliases.
+type Policy struct {
+	Mode Mode
+	// Aliases maps an element to the name it stands for, for example
+	// "display" to "show". An alias only applies where that name is
+	// available.
+	Aliases map[string]string
+}
+
+type policyKey struct{}
+
+// NewContext returns a copy of ctx carrying p
+func NewContext(ctx context.Context, p *Policy) context.Context {
+	return context.WithValue(ctx, policyKey{}, p)
+}
+
+// FromContext returns the policy carried by ctx, or nil
+func FromContext(ctx context.Context) *Policy {
+	if ctx == nil {
+		return nil
+	}
+	p, _ := ctx.Value(policyKey{}).(*Policy)
+	return p
+}
+
+func (p *Policy) fold(s string) string {
+	if p == nil || p.Mode == Prefix {
+		return s
+	}
+	return strings.ToLower(s)
+}
+
+func (p *Policy) alias(val string) (string, bool) {
+	if p == nil || len(p.Aliases) == 0 {
+		return "", false
+	}
+	if name, ok := p.Aliases[val]; ok {
+		return name, true
+	}
+	// Where aliases differ only in case, the first in sorted order
+	// applies, whatever the map's order
+	aliases := make([]string, 0, len(p.Aliases))
+	for a := range p.Aliases {
+		aliases = append(aliases, a)
+	}
+	sort.Strings(aliases)
+	for _, a := range aliases {
+		if p.fold(a) == p.fold(val) {
+			return p.Aliases[a], true
+		}
+	}
+	return "", false
+}
+
+// Exact reports whether val names name exactly, without abbreviation
+func (p *Policy) Exact(name, val string) bool {
+	if name == val || p.fold(name) == p.fold(val) {
+		return true
+	}
+	a, ok := p.alias(val)
+	return ok && a == name
+}
+
+func (p *Policy) prefix(name, val string) bool {
+	return strings.HasPrefix(p.fold(name), p.fold(val))
+}
+
+func subsequence(name, val string) bool {
+	name, val = strings.ToLower(name), strings.ToLower(val)
+	for _, r := range val {
+		i := strings.IndexRune(name, r)
+		if i < 0 {
+			return false
+		}
+		name = name[i+len(string(r)):]
+	}
+	return true
+}
+
+// Filter returns those of names which val matches, in their original
+// order. More than one name being returned is ambiguous unless one of
+// them is an Exact match.
+func (p *Policy) Filter(val string, names []string) []string {
+	var matched []string
+	alias, hasAlias := p.alias(val)
+	for _, name := range names {
+		if p.prefix(name, val) || (hasAlias && alias == name) {
+			matched = append(matched, name)
+		}
+	}
+	if len(matched) > 0 || p == nil || p.Mode != Subsequence {
+		return matched
+	}
+	for _, name := range names {
+		if subsequence(name, val) {
+			matched = append(matched, name)
+		}
+	}
+	return matched
+}
+
+// Children returns the names of the commands which may follow the
+// expanded path, and whether a value may be given there instead.
+type Children func(path []string) (names []string, value bool)
+
+// Split returns path with each element which matches no command at its
+// position, but which divides into parts matching the commands at
+// successive positions, replaced by the names of those commands. Under
+// the Subsequence policy "shintf" thus becomes "show interfaces".
+// Elements are only divided where no value may be given, and only if
+// they divide in a single way; otherwise they and those following are
+// left for expansion to report. Other policies return path unchanged.
+func (p *Policy) Split(path []string, children Children) []string {
+	if p == nil || p.Mode != Subsequence {
+		return path
+	}
+	var out, ep []string
+	for i, v := range path {
+		names, value := children(ep)
+		matched := p.Filter(v, names)
+		switch {
+		case len(matched) > 0:
+			name, ok := p.unique(v, matched)
+			if !ok {
+				return append(out, path[i:]...)
+			}
+			out, ep = append(out, v), append(ep, name)
+		case value:
+			out, ep = append(out, v), append(ep, v)
+		default:
+			parts := p.divide(v, ep, children)
+			if parts == nil {
+				return append(out, path[i:]...)
+			}
+			out, ep = append(out, parts...), append(ep, parts...)
+		}
+	}
+	return out
+}
+
+// unique returns the name val matches among matched, unless it is
+// ambiguous
+func (p *Policy) unique(val string, matched []string) (string, bool) {
+	if len(matched) == 1 {
+		return matched[0], true
+	}
+	for _, name := range matched {
+		if p.Exact(name, val) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// divide returns the names of the commands following ep which the parts
+// of val match, if val divides between them in exactly one way.
+func (p *Policy) divide(val string, ep []string, children Children) []string {
+	found := make(map[string][]string)
+	p.divisions(val, ep, nil, children, found)
+	if len(found) != 1 {
+		return nil
+	}
+	for _, parts := range found {
+		return parts
+	}
+	return nil
+}
+
+// divisions adds to found each way the remainder of an element, val,
+// divides between the commands following ep, keyed by the names of the
+// commands. parts holds the names already matched by the element.
+func (p *Policy) divisions(
+	val string,
+	ep, parts []string,
+	children Children,
+	found map[string][]string,
+) {
+	names, _ := children(ep)
+	for k := 1; k <= len(val); k++ {
+		head, tail := val[:k], val[k:]
+		for _, name := range p.Filter(head, names) {
+			nparts := append(append([]string(nil), parts...), name)
+			if tail == "" {
+				if len(nparts) > 1 {
+					found[strings.Join(nparts, " ")] = nparts
+				}
+				continue
+			}
+			p.divisions(tail, append(append([]string(nil), ep...), name),
+				nparts, children, found)
+		}
+	}
+}
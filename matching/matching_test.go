@@ -0,0 +1,146 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package matching
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var names = []string{"show", "shutdown", "interfaces", "internal", "ping"}
+
+func TestFilter(t *testing.T) {
+	aliases := map[string]string{"display": "show", "int": "interfaces"}
+
+	tests := []struct {
+		policy *Policy
+		val    string
+		expect []string
+	}{
+		{nil, "sh", []string{"show", "shutdown"}},
+		{nil, "SH", nil},
+		{nil, "intf", nil},
+		{&Policy{Mode: CaseInsensitive}, "SH", []string{"show", "shutdown"}},
+		{&Policy{Mode: CaseInsensitive}, "Pi", []string{"ping"}},
+		{&Policy{Mode: CaseInsensitive}, "intf", nil},
+		{&Policy{Mode: Subsequence}, "intf", []string{"interfaces"}},
+		{&Policy{Mode: Subsequence}, "shw", []string{"show", "shutdown"}},
+		{&Policy{Mode: Subsequence}, "pg", []string{"ping"}},
+		{&Policy{Mode: Subsequence}, "sh", []string{"show", "shutdown"}},
+		{&Policy{Mode: Subsequence}, "inl", []string{"internal"}},
+		{&Policy{Aliases: aliases}, "display", []string{"show"}},
+		{&Policy{Aliases: aliases}, "int", []string{"interfaces", "internal"}},
+		{&Policy{Mode: CaseInsensitive, Aliases: aliases}, "DISPLAY", []string{"show"}},
+	}
+
+	for _, test := range tests {
+		got := test.policy.Filter(test.val, names)
+		if !reflect.DeepEqual(got, test.expect) {
+			t.Errorf("%v filtering '%s': expected %v, got %v\n",
+				test.policy, test.val, test.expect, got)
+		}
+	}
+}
+
+func TestExact(t *testing.T) {
+	aliases := map[string]string{"int": "interfaces"}
+
+	if !(*Policy)(nil).Exact("show", "show") || (*Policy)(nil).Exact("show", "SHOW") {
+		t.Errorf("Unexpected default exact matching\n")
+	}
+	if !(&Policy{Mode: CaseInsensitive}).Exact("show", "SHOW") {
+		t.Errorf("Expected case-insensitive exact match\n")
+	}
+	p := &Policy{Aliases: aliases}
+	if !p.Exact("interfaces", "int") || p.Exact("internal", "int") {
+		t.Errorf("Expected alias to match exactly\n")
+	}
+
+	// Aliases which differ only in case resolve the same way every time
+	p = &Policy{Mode: CaseInsensitive, Aliases: map[string]string{
+		"Int": "internal", "int": "interfaces", "INT": "internal"}}
+	for i := 0; i < 20; i++ {
+		if !p.Exact("internal", "iNt") || p.Exact("interfaces", "iNt") {
+			t.Fatalf("Expected first alias in sorted order to match\n")
+		}
+	}
+}
+
+func TestContext(t *testing.T) {
+	if FromContext(context.Background()) != nil {
+		t.Fatalf("Expected no policy by default\n")
+	}
+	p := &Policy{Mode: Subsequence}
+	if FromContext(NewContext(context.Background(), p)) != p {
+		t.Fatalf("Policy not carried by context\n")
+	}
+}
+
+// testChildren is a command tree of keywords, "<value>" marking where a
+// value may be given
+var testChildren = map[string][]string{
+	"":                    {"show", "shutdown", "ping"},
+	"show":                {"interfaces", "internal", "ip"},
+	"show interfaces":     {"ethernet", "loopback"},
+	"show ip":             {"route"},
+	"show interfaces eth": {"<value>"},
+	"ping":                {"<value>"},
+}
+
+func childrenOf(path []string) ([]string, bool) {
+	key := strings.Join(path, " ")
+	if len(path) > 3 && path[0] == "show" && path[1] == "interfaces" {
+		key = "show interfaces eth"
+	}
+	if key == "show interfaces ethernet" {
+		key = "show interfaces eth"
+	}
+	var names []string
+	value := false
+	for _, name := range testChildren[key] {
+		if name == "<value>" {
+			value = true
+		} else {
+			names = append(names, name)
+		}
+	}
+	return names, value
+}
+
+func TestSplit(t *testing.T) {
+	sub := &Policy{Mode: Subsequence}
+
+	tests := []struct {
+		policy *Policy
+		path   []string
+		expect []string
+	}{
+		{sub, []string{"shintf"}, []string{"show", "interfaces"}},
+		{sub, []string{"shiproute"}, []string{"show", "ip", "route"}},
+		{sub, []string{"shintfeth", "dp0s3"},
+			[]string{"show", "interfaces", "ethernet", "dp0s3"}},
+		{sub, []string{"show", "intflo"}, []string{"show", "interfaces", "loopback"}},
+		// An ambiguous element stops splitting
+		{sub, []string{"sh", "intflo"}, []string{"sh", "intflo"}},
+		{sub, []string{"sho", "int", "eth"}, []string{"sho", "int", "eth"}},
+		// Values are never divided
+		{sub, []string{"ping", "shintf"}, []string{"ping", "shintf"}},
+		// Nor are elements which divide in more than one way
+		{sub, []string{"shint"}, []string{"shint"}},
+		{sub, []string{"shwo"}, []string{"shwo"}},
+		{nil, []string{"shintf"}, []string{"shintf"}},
+		{&Policy{Mode: CaseInsensitive}, []string{"shintf"}, []string{"shintf"}},
+	}
+
+	for _, test := range tests {
+		got := test.policy.Split(test.path, childrenOf)
+		if !reflect.DeepEqual(got, test.expect) {
+			t.Errorf("%v splitting %v: expected %v, got %v\n",
+				test.policy, test.path, test.expect, got)
+		}
+	}
+}
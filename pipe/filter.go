@@ -0,0 +1,200 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package pipe
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// emitFunc passes a line on to the next stage of a pipeline
+type emitFunc func(line string)
+
+// lineFilter is an instance of a filter applied to an output stream a
+// line at a time. end is called, if not nil, once all the output has
+// been seen.
+type lineFilter struct {
+	line func(s string, emit emitFunc)
+	end  func(emit emitFunc)
+}
+
+type filter struct {
+	name, help    string
+	arg, argHelp  string
+	newLineFilter func(re *regexp.Regexp) *lineFilter
+}
+
+var filters = map[string]*filter{
+	"match": {
+		name: "match", help: "Show only lines matching a pattern",
+		arg: "pattern", argHelp: "Regular expression to match",
+		newLineFilter: func(re *regexp.Regexp) *lineFilter {
+			return &lineFilter{line: func(s string, emit emitFunc) {
+				if re.MatchString(s) {
+					emit(s)
+				}
+			}}
+		},
+	},
+	"except": {
+		name: "except", help: "Show only lines not matching a pattern",
+		arg: "pattern", argHelp: "Regular expression to exclude",
+		newLineFilter: func(re *regexp.Regexp) *lineFilter {
+			return &lineFilter{line: func(s string, emit emitFunc) {
+				if !re.MatchString(s) {
+					emit(s)
+				}
+			}}
+		},
+	},
+	"begin": {
+		name: "begin", help: "Show output from the first line matching a pattern",
+		arg: "pattern", argHelp: "Regular expression to begin at",
+		newLineFilter: func(re *regexp.Regexp) *lineFilter {
+			begun := false
+			return &lineFilter{line: func(s string, emit emitFunc) {
+				if begun = begun || re.MatchString(s); begun {
+					emit(s)
+				}
+			}}
+		},
+	},
+	"section": {
+		name: "section", help: "Show sections whose heading matches a pattern",
+		arg: "pattern", argHelp: "Regular expression to match headings",
+		newLineFilter: newSectionFilter,
+	},
+	"count": {
+		name: "count", help: "Count the lines of output",
+		newLineFilter: func(*regexp.Regexp) *lineFilter {
+			n := 0
+			return &lineFilter{
+				line: func(string, emitFunc) { n++ },
+				end:  func(emit emitFunc) { emit(fmt.Sprintf("Count: %d lines", n)) },
+			}
+		},
+	},
+	"no-more": {
+		name: "no-more", help: "Do not paginate output",
+		newLineFilter: func(*regexp.Regexp) *lineFilter {
+			return &lineFilter{line: func(s string, emit emitFunc) { emit(s) }}
+		},
+	},
+}
+
+// newSectionFilter shows each line matching re along with the lines
+// following it which are indented further, as the contents of the
+// section it heads
+func newSectionFilter(re *regexp.Regexp) *lineFilter {
+	inSection := false
+	indent := 0
+	return &lineFilter{line: func(s string, emit emitFunc) {
+		ind := len(s) - len(strings.TrimLeft(s, " \t"))
+		switch {
+		case re.MatchString(s):
+			inSection, indent = true, ind
+		case inSection && ind > indent && strings.TrimSpace(s) != "":
+		default:
+			inSection = false
+			return
+		}
+		emit(s)
+	}}
+}
+
+func (f *filter) validate(s Stage) error {
+	switch {
+	case f.arg == "" && len(s.Args) > 0:
+		return fmt.Errorf("Invalid filter: %s [%s]", f.name, s.Args[0])
+	case f.arg != "" && len(s.Args) == 0:
+		return fmt.Errorf("Incomplete filter: %s <%s>", f.name, f.arg)
+	case len(s.Args) > 1:
+		return fmt.Errorf("Invalid filter: %s %s [%s]\n\n"+
+			"  Quote a %s containing spaces", f.name, s.Args[0], s.Args[1], f.arg)
+	case f.arg != "":
+		if _, err := regexp.Compile(s.Args[0]); err != nil {
+			return fmt.Errorf("Invalid %s for %s: %s", f.arg, f.name, err)
+		}
+	}
+	return nil
+}
+
+func (f *filter) lineFilter(s Stage) *lineFilter {
+	var re *regexp.Regexp
+	if f.arg != "" {
+		re = regexp.MustCompile(s.Args[0])
+	}
+	return f.newLineFilter(re)
+}
+
+// filterWriter passes the lines written to it through each stage of a
+// pipeline in turn, writing what remains to w
+type filterWriter struct {
+	w      io.Writer
+	buf    bytes.Buffer
+	stages []*lineFilter
+	err    error
+}
+
+// Filter returns a writer applying the pipeline's filters to the output
+// written to it, writing the result to w. It must be closed once all the
+// output has been written, to complete any filter waiting for the end
+// of the output.
+func (p *Pipeline) Filter(w io.Writer) io.WriteCloser {
+	fw := &filterWriter{w: w}
+	for _, s := range p.Stages {
+		fw.stages = append(fw.stages, filters[s.Name].lineFilter(s))
+	}
+	return fw
+}
+
+// emitter returns the function passing lines to stage i
+func (fw *filterWriter) emitter(i int) emitFunc {
+	if i == len(fw.stages) {
+		return func(s string) {
+			if fw.err == nil {
+				_, fw.err = io.WriteString(fw.w, s+"\n")
+			}
+		}
+	}
+	next := fw.emitter(i + 1)
+	return func(s string) { fw.stages[i].line(s, next) }
+}
+
+func (fw *filterWriter) Write(p []byte) (int, error) {
+	fw.buf.Write(p)
+	emit := fw.emitter(0)
+	for {
+		i := bytes.IndexByte(fw.buf.Bytes(), '\n')
+		if i < 0 {
+			break
+		}
+		line := string(fw.buf.Next(i + 1))
+		emit(line[:i])
+	}
+	if fw.err != nil {
+		return 0, fw.err
+	}
+	return len(p), nil
+}
+
+// Close passes on any final unterminated line, then ends each stage in
+// turn so that what it emits passes through the stages after it
+func (fw *filterWriter) Close() error {
+	if fw.buf.Len() > 0 {
+		line := fw.buf.String()
+		fw.buf.Reset()
+		fw.emitter(0)(line)
+	}
+	for i, s := range fw.stages {
+		if s.end != nil {
+			s.end(fw.emitter(i + 1))
+		}
+	}
+	return fw.err
+}
@@ -0,0 +1,169 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+// Package pipe implements output filters given after an operational
+// command, such as "show log | match error | count". The command line
+// is split at unquoted pipes; the command before the first is expanded
+// as usual and each filter after it is validated, then applied to the
+// command's output as it is written.
+package pipe
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/danos/op/matching"
+	"github.com/danos/op/suggest"
+	"github.com/danos/op/tokenizer"
+)
+
+// Stage is a filter applied to the output of a command
+type Stage struct {
+	Name string
+	Args []string
+}
+
+func (s Stage) String() string {
+	return strings.Join(append([]string{s.Name}, s.Args...), " ")
+}
+
+// Pipeline is a command followed by the filters its output is passed
+// through, in order
+type Pipeline struct {
+	Command []string
+	Stages  []Stage
+}
+
+// ExpandFunc expands the path of a command, as yang.ExpandContext or
+// tree.Expand do
+type ExpandFunc func(path []string) ([]string, error)
+
+// Parse splits line at unquoted pipes, expanding the command before the
+// first with expand, if not nil, and validating the filters after it.
+// Filter names may be abbreviated.
+func Parse(line string, expand ExpandFunc) (*Pipeline, error) {
+	segs, err := split(line)
+	if err != nil {
+		return nil, err
+	}
+	p := &Pipeline{Command: segs[0]}
+	if expand != nil {
+		if p.Command, err = expand(p.Command); err != nil {
+			return nil, err
+		}
+	}
+	for _, seg := range segs[1:] {
+		if len(seg) == 0 {
+			return nil, fmt.Errorf("Missing filter after |")
+		}
+		f, err := lookup(seg[0])
+		if err != nil {
+			return nil, err
+		}
+		stage := Stage{Name: f.name, Args: seg[1:]}
+		if err := f.validate(stage); err != nil {
+			return nil, err
+		}
+		p.Stages = append(p.Stages, stage)
+	}
+	return p, nil
+}
+
+// Paged returns false if the output should not be passed to a pager
+func (p *Pipeline) Paged() bool {
+	for _, s := range p.Stages {
+		if s.Name == "no-more" {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *Pipeline) String() string {
+	s := strings.Join(p.Command, " ")
+	for _, st := range p.Stages {
+		s += " | " + st.String()
+	}
+	return s
+}
+
+// lookup returns the filter val names, abbreviated or not
+func lookup(val string) (*filter, error) {
+	names := filterNames()
+	var policy *matching.Policy
+	matches := policy.Filter(val, names)
+	for _, name := range matches {
+		if policy.Exact(name, val) {
+			return filters[name], nil
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("Invalid filter: [%s]%s", val,
+			suggest.Format(suggest.Rank(val, names)))
+	case 1:
+		return filters[matches[0]], nil
+	}
+	return nil, fmt.Errorf("Ambiguous filter: [%s]\n\n  Possible completions:\n  %s",
+		val, strings.Join(matches, "\n  "))
+}
+
+func filterNames() []string {
+	names := make([]string, 0, len(filters))
+	for name := range filters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Complete returns the completions of the last word of line, and their
+// help text, when it is part of a filter. The last word is the one being
+// typed, and is empty if line ends in a space or pipe. ok is false if
+// the last word is part of the command, which is completed as usual.
+func Complete(line string) (comps map[string]string, ok bool) {
+	segs, partial, err := splitPartial(line)
+	if err != nil || len(segs) < 2 {
+		return nil, false
+	}
+	seg := segs[len(segs)-1]
+	if partial {
+		seg = seg[:len(seg)-1]
+	}
+
+	comps = make(map[string]string)
+	switch len(seg) {
+	case 0:
+		for name, f := range filters {
+			comps[name] = f.help
+		}
+	case 1:
+		if f, err := lookup(seg[0]); err == nil && f.arg != "" {
+			comps["<"+f.arg+">"] = f.argHelp
+		}
+	}
+	return comps, true
+}
+
+// split splits line into its unquoted pipe separated segments, each
+// split into words with quotes removed
+func split(line string) ([][]string, error) {
+	segs, _, err := splitPartial(line)
+	return segs, err
+}
+
+// splitPartial is split, also returning whether the last word is
+// unfinished; that is, not followed by a space
+func splitPartial(line string) ([][]string, bool, error) {
+	l, err := tokenizer.Split(line, len(line))
+	if err != nil {
+		return nil, false, err
+	}
+	var segs [][]string
+	for _, seg := range l.Segments() {
+		segs = append(segs, tokenizer.Values(seg))
+	}
+	return segs, l.InToken, nil
+}
@@ -0,0 +1,182 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package pipe
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	expand := func(path []string) ([]string, error) {
+		if len(path) > 0 && path[0] == "sh" {
+			path[0] = "show"
+		}
+		return path, nil
+	}
+
+	tests := []struct {
+		line, expect, err string
+	}{
+		{"sh log", "show log", ""},
+		{"sh log | m error | cou", "show log | match error | count", ""},
+		{`sh log | match "link (up|down)" | no`,
+			"show log | match link (up|down) | no-more", ""},
+		{`sh log | except 'a|b'`, "show log | except a|b", ""},
+		{`sh log \| x`, "show log | x", ""},
+		{"sh log |", "", "Missing filter after |"},
+		{"sh log | mtch x", "",
+			"Invalid filter: [mtch]\n\n  Did you mean:\n  match"},
+		{"sh log | c", "show log | count", ""},
+		{"sh log | match", "", "Incomplete filter: match <pattern>"},
+		{"sh log | count x", "", "Invalid filter: count [x]"},
+		{"sh log | match link up", "", "Invalid filter: match link [up]\n\n" +
+			"  Quote a pattern containing spaces"},
+		{"sh log | match (", "", "Invalid pattern for match: " +
+			"error parsing regexp: missing closing ): `(`"},
+		{`sh log | match "x`, "", "Unterminated quote at column 16"},
+	}
+
+	for _, test := range tests {
+		p, err := Parse(test.line, expand)
+		if test.expect == "" {
+			if err == nil {
+				t.Errorf("%s: expected failure, got %s\n", test.line, p)
+			} else if test.err != "" && err.Error() != test.err {
+				t.Errorf("%s:\n Expected error - %q\n Got - %q\n",
+					test.line, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected failure: %s\n", test.line, err)
+			continue
+		}
+		if p.String() != test.expect {
+			t.Errorf("%s:\n Expected - %s\n Got - %s\n", test.line, test.expect, p)
+		}
+	}
+}
+
+func TestParseExpandFailure(t *testing.T) {
+	_, err := Parse("bogus | count", func([]string) ([]string, error) {
+		return nil, fmt.Errorf("Invalid command: [bogus]")
+	})
+	if err == nil || err.Error() != "Invalid command: [bogus]" {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+}
+
+const testOutput = `interfaces {
+    dataplane dp0s3 {
+        address dhcp
+    }
+    loopback lo
+}
+system {
+    host-name vyatta
+    login {
+        user vyatta
+    }
+}
+`
+
+func checkFilter(t *testing.T, line, input, expect string) {
+	t.Helper()
+	p, err := Parse(line, nil)
+	if err != nil {
+		t.Fatalf("%s: unexpected failure: %s\n", line, err)
+	}
+	var out bytes.Buffer
+	w := p.Filter(&out)
+	// Write in small pieces so that lines are split across writes
+	for r := strings.NewReader(input); ; {
+		chunk := make([]byte, 7)
+		n, err := r.Read(chunk)
+		w.Write(chunk[:n])
+		if err == io.EOF {
+			break
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("%s: unexpected failure: %s\n", line, err)
+	}
+	if out.String() != expect {
+		t.Errorf("%s:\n Expected -\n%s\n Got -\n%s\n", line, expect, out.String())
+	}
+}
+
+func TestFilters(t *testing.T) {
+	checkFilter(t, "show | match vyatta", testOutput,
+		"    host-name vyatta\n        user vyatta\n")
+	checkFilter(t, "show | except '^ '", testOutput,
+		"interfaces {\n}\nsystem {\n}\n")
+	checkFilter(t, "show | begin ^system", testOutput,
+		"system {\n    host-name vyatta\n    login {\n        user vyatta\n    }\n}\n")
+	checkFilter(t, "show | section dataplane", testOutput,
+		"    dataplane dp0s3 {\n        address dhcp\n")
+	checkFilter(t, "show | section ^system", testOutput,
+		"system {\n    host-name vyatta\n    login {\n        user vyatta\n    }\n")
+	checkFilter(t, "show | count", testOutput, "Count: 12 lines\n")
+	checkFilter(t, "show | match vyatta | count", testOutput, "Count: 2 lines\n")
+	checkFilter(t, "show | count | match Count", testOutput, "Count: 12 lines\n")
+	checkFilter(t, "show | no-more", "no newline", "no newline\n")
+}
+
+func TestPaged(t *testing.T) {
+	for line, paged := range map[string]bool{
+		"show log":                true,
+		"show log | match x":      true,
+		"show log | no-more":      false,
+		"show log | no | match x": false,
+	} {
+		p, err := Parse(line, nil)
+		if err != nil || p.Paged() != paged {
+			t.Errorf("%s: expected paged %t, got %v\n", line, paged, err)
+		}
+	}
+}
+
+func TestComplete(t *testing.T) {
+	tests := []struct {
+		line   string
+		ok     bool
+		expect []string
+	}{
+		{"show log", false, nil},
+		{"show log ", false, nil},
+		{"show log |", true, []string{"begin", "count", "except", "match",
+			"no-more", "section"}},
+		{"show log | m", true, []string{"begin", "count", "except", "match",
+			"no-more", "section"}},
+		{"show log | match ", true, []string{"<pattern>"}},
+		{"show log | ma", true, []string{"begin", "count", "except", "match",
+			"no-more", "section"}},
+		{"show log | count ", true, nil},
+		{"show log | match x | ", true, []string{"begin", "count", "except",
+			"match", "no-more", "section"}},
+		{`show log "|`, false, nil},
+	}
+
+	for _, test := range tests {
+		comps, ok := Complete(test.line)
+		if ok != test.ok {
+			t.Errorf("%q: expected ok %t\n", test.line, test.ok)
+			continue
+		}
+		if len(comps) != len(test.expect) {
+			t.Errorf("%q: unexpected completions %v\n", test.line, comps)
+			continue
+		}
+		for _, c := range test.expect {
+			if _, ok := comps[c]; !ok {
+				t.Errorf("%q: missing completion %s in %v\n", test.line, c, comps)
+			}
+		}
+	}
+}
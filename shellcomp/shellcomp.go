@@ -0,0 +1,283 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+// Package shellcomp generates standalone bash and zsh completion
+// scripts for the operational commands of a command tree, so that they
+// may be completed without the commands' daemon.
+//
+// The tree is encoded in tables of numbered nodes: the keywords of each
+// node, the node each keyword leads to, the argument of each node, and
+// the values of each argument with a fixed set. The scripts walk the
+// words typed so far through the tables as the CLI would, matching
+// keywords exactly or by unique prefix and otherwise taking a word as
+// the value of an argument.
+package shellcomp
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/danos/op/cmdtree"
+	"github.com/danos/op/tmpl"
+)
+
+// Script is a completion script for the commands of a tree
+type Script struct {
+	Root *cmdtree.Node
+	// Command is the command taking the commands of the tree as its
+	// arguments. If empty, each top level keyword is completed as a
+	// command of its own.
+	Command string
+}
+
+type table struct {
+	Prefix   string
+	Commands []string
+	First    int
+
+	Words map[int]string
+	Descs map[int]string
+	Next  map[string]int
+	Arg   map[int]int
+	Vals  map[int]string
+	Hints map[int]string
+}
+
+var unsafe = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// table numbers the nodes of the tree breadth first from the root,
+// which is 0. Hidden keywords are left out of the keywords completed,
+// but may still be walked through when typed in full.
+func (s *Script) table() *table {
+	t := &table{
+		Prefix: "_opcomp",
+		Words:  make(map[int]string),
+		Descs:  make(map[int]string),
+		Next:   make(map[string]int),
+		Arg:    make(map[int]int),
+		Vals:   make(map[int]string),
+		Hints:  make(map[int]string),
+	}
+	if s.Command != "" {
+		t.Prefix += "_" + unsafe.ReplaceAllString(s.Command, "_")
+		t.Commands = []string{s.Command}
+		t.First = 1
+	}
+
+	queue := []*cmdtree.Node{s.Root}
+	for id := 0; id < len(queue); id++ {
+		n := queue[id]
+		var words, descs []string
+		for _, c := range n.Keywords() {
+			t.Next[fmt.Sprintf("%d:%s", id, c.Name)] = len(queue)
+			queue = append(queue, c)
+			if c.Hidden {
+				continue
+			}
+			words = append(words, c.Name)
+			descs = append(descs, describe(c.Name, c.Help))
+			if id == 0 && s.Command == "" {
+				t.Commands = append(t.Commands, c.Name)
+			}
+		}
+		if len(words) > 0 {
+			t.Words[id] = strings.Join(words, " ")
+			t.Descs[id] = strings.Join(descs, "\n")
+		}
+		if arg := n.Argument(); arg != nil && !arg.Hidden {
+			argID := len(queue)
+			queue = append(queue, arg)
+			t.Arg[id] = argID
+			if len(arg.Values) > 0 {
+				t.Vals[argID] = strings.Join(arg.Values, " ")
+			}
+			t.Hints[argID] = hint(arg)
+		}
+	}
+	sort.Strings(t.Commands)
+	return t
+}
+
+// describe returns a zsh description of a completion
+func describe(name, help string) string {
+	name = strings.Replace(name, ":", `\:`, -1)
+	help = strings.Join(strings.Fields(help), " ")
+	if help == "" {
+		return name
+	}
+	return name + ":" + help
+}
+
+// hint returns the hint shown by zsh for an argument
+func hint(arg *cmdtree.Node) string {
+	help := strings.Join(strings.Fields(arg.Help), " ")
+	if help == "" {
+		return arg.Placeholder()
+	}
+	return arg.Placeholder() + " - " + help
+}
+
+var funcs = template.FuncMap{
+	"quote": tmpl.ShellQuote,
+	"sortedInts": func(m interface{}) []int {
+		var keys []int
+		switch m := m.(type) {
+		case map[int]string:
+			for k := range m {
+				keys = append(keys, k)
+			}
+		case map[int]int:
+			for k := range m {
+				keys = append(keys, k)
+			}
+		}
+		sort.Ints(keys)
+		return keys
+	},
+	"sortedStrings": func(m map[string]int) []string {
+		var keys []string
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		return keys
+	},
+}
+
+// WriteBash writes a bash completion script, which needs bash 4.2 or
+// later
+func (s *Script) WriteBash(w io.Writer) error {
+	return bashTemplate.Execute(w, s.table())
+}
+
+// WriteZsh writes a zsh completion script, to be installed as a
+// function in $fpath or sourced once compinit has run
+func (s *Script) WriteZsh(w io.Writer) error {
+	return zshTemplate.Execute(w, s.table())
+}
+
+var bashTemplate = template.Must(template.New("bash").Funcs(funcs).Parse(
+	`# bash completion for operational commands
+# Generated by opparse completion; do not edit.
+
+{{$t := .}}declare -gA {{.Prefix}}_words=({{range sortedInts .Words}}
+	[{{.}}]={{quote (index $t.Words .)}}{{end}}
+)
+declare -gA {{.Prefix}}_next=({{range sortedStrings .Next}}
+	[{{quote .}}]={{index $t.Next .}}{{end}}
+)
+declare -gA {{.Prefix}}_arg=({{range sortedInts .Arg}}
+	[{{.}}]={{index $t.Arg .}}{{end}}
+)
+declare -gA {{.Prefix}}_vals=({{range sortedInts .Vals}}
+	[{{.}}]={{quote (index $t.Vals .)}}{{end}}
+)
+
+# {{.Prefix}}_step sets {{.Prefix}}_node to the node reached from node
+# $1 by the word $2, failing if there is none
+{{.Prefix}}_step() {
+	local node=$1 word=$2 kw key
+	local -a matches=()
+	key="$node:$word"
+	{{.Prefix}}_node=${ {{- .Prefix}}_next[$key]}
+	[[ -n ${{.Prefix}}_node ]] && return 0
+	for kw in ${ {{- .Prefix}}_words[$node]}; do
+		[[ $kw == "$word"* ]] && matches+=("$kw")
+	done
+	if (( ${#matches[@]} == 1 )); then
+		key="$node:${matches[0]}"
+		{{.Prefix}}_node=${ {{- .Prefix}}_next[$key]}
+		return 0
+	fi
+	{{.Prefix}}_node=${ {{- .Prefix}}_arg[$node]}
+	[[ -n ${{.Prefix}}_node ]]
+}
+
+{{.Prefix}}() {
+	local cur=${COMP_WORDS[COMP_CWORD]} i words arg
+	{{.Prefix}}_node=0
+	for ((i = {{.First}}; i < COMP_CWORD; i++)); do
+		{{.Prefix}}_step "${{.Prefix}}_node" "${COMP_WORDS[i]}" || return 0
+	done
+	words=${ {{- .Prefix}}_words[${{.Prefix}}_node]}
+	arg=${ {{- .Prefix}}_arg[${{.Prefix}}_node]}
+	[[ -n $arg ]] && words+=" ${ {{- .Prefix}}_vals[$arg]}"
+	COMPREPLY=($(compgen -W "$words" -- "$cur"))
+}
+
+complete -F {{.Prefix}}{{range .Commands}} {{quote .}}{{end}}
+`))
+
+var zshTemplate = template.Must(template.New("zsh").Funcs(funcs).Parse(
+	`#compdef{{range .Commands}} {{.}}{{end}}
+# zsh completion for operational commands
+# Generated by opparse completion; do not edit.
+
+{{$t := .}}typeset -gA {{.Prefix}}_words {{.Prefix}}_descs {{.Prefix}}_next {{.Prefix}}_arg {{.Prefix}}_vals {{.Prefix}}_hints
+{{.Prefix}}_words=({{range sortedInts .Words}}
+	{{.}} {{quote (index $t.Words .)}}{{end}}
+)
+{{.Prefix}}_descs=({{range sortedInts .Descs}}
+	{{.}} {{quote (index $t.Descs .)}}{{end}}
+)
+{{.Prefix}}_next=({{range sortedStrings .Next}}
+	{{quote .}} {{index $t.Next .}}{{end}}
+)
+{{.Prefix}}_arg=({{range sortedInts .Arg}}
+	{{.}} {{index $t.Arg .}}{{end}}
+)
+{{.Prefix}}_vals=({{range sortedInts .Vals}}
+	{{.}} {{quote (index $t.Vals .)}}{{end}}
+)
+{{.Prefix}}_hints=({{range sortedInts .Hints}}
+	{{.}} {{quote (index $t.Hints .)}}{{end}}
+)
+
+# {{.Prefix}}_step sets REPLY to the node reached from node $1 by the
+# word $2, failing if there is none
+{{.Prefix}}_step() {
+	local node=$1 word=$2 kw key
+	local -a matches
+	key="$node:$word"
+	REPLY=${ {{- .Prefix}}_next[$key]}
+	[[ -n $REPLY ]] && return 0
+	for kw in ${= {{- .Prefix}}_words[$node]}; do
+		[[ $kw == ${word}* ]] && matches+=($kw)
+	done
+	if (( $#matches == 1 )); then
+		key="$node:$matches[1]"
+		REPLY=${ {{- .Prefix}}_next[$key]}
+		return 0
+	fi
+	REPLY=${ {{- .Prefix}}_arg[$node]}
+	[[ -n $REPLY ]]
+}
+
+{{.Prefix}}() {
+	local node=0 i arg REPLY expl
+	local -a descs vals
+	for ((i = {{.First}} + 1; i < CURRENT; i++)); do
+		{{.Prefix}}_step $node "$words[i]" || return 1
+		node=$REPLY
+	done
+	if [[ -n ${ {{- .Prefix}}_descs[$node]} ]]; then
+		descs=("${(@f){{.Prefix}}_descs[$node]}")
+		_describe -t keywords keyword descs
+	fi
+	arg=${ {{- .Prefix}}_arg[$node]}
+	[[ -z $arg ]] && return
+	if [[ -n ${ {{- .Prefix}}_vals[$arg]} ]]; then
+		vals=(${= {{- .Prefix}}_vals[$arg]})
+		_wanted values expl "${ {{- .Prefix}}_hints[$arg]}" compadd -a vals
+	else
+		_message -e values "${ {{- .Prefix}}_hints[$arg]}"
+	fi
+}
+
+{{.Prefix}} "$@"
+`))
@@ -0,0 +1,132 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package shellcomp
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/danos/op/cmdtree"
+)
+
+func kw(name, help string, children ...*cmdtree.Node) *cmdtree.Node {
+	return &cmdtree.Node{Name: name, Help: help, Children: children}
+}
+
+func arg(name string, values []string, children ...*cmdtree.Node) *cmdtree.Node {
+	return &cmdtree.Node{Name: name, Arg: true, Values: values, Children: children}
+}
+
+func testTree() *cmdtree.Node {
+	debug := kw("debug", "Show debugging")
+	debug.Hidden = true
+	root := &cmdtree.Node{Children: []*cmdtree.Node{
+		kw("show", "Show system information",
+			debug,
+			kw("interfaces", "Show interfaces",
+				arg("interfaces", nil, kw("brief", "Show a summary"))),
+			kw("log", "Show the log", kw("tail", "Follow the log")),
+			kw("login", "Show logins"),
+		),
+		kw("reset", "Reset a counter",
+			arg("counter", []string{"ip", "ipv6"})),
+	}}
+	root.Sort()
+	return root
+}
+
+// bashComplete returns the completions of the line of words by the bash
+// script, the last word being completed
+func bashComplete(t *testing.T, script string, words ...string) string {
+	var quoted []string
+	for _, w := range words {
+		quoted = append(quoted, "'"+w+"'")
+	}
+	run := script + "\nCOMP_WORDS=(" + strings.Join(quoted, " ") + ")\n" +
+		"COMP_CWORD=$((${#COMP_WORDS[@]} - 1))\n" +
+		"$(complete -p " + quoted[0] + " | sed 's/.*-F \\([^ ]*\\).*/\\1/')\n" +
+		"echo ${COMPREPLY[@]}\n"
+	out, err := exec.Command("bash", "-c", run).CombinedOutput()
+	if err != nil {
+		t.Fatalf("Running completion failed: %s\n%s\n", err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestBash(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash is not available")
+	}
+
+	tests := []struct {
+		command  string
+		words    []string
+		expected string
+	}{
+		{"", []string{"show", ""}, "interfaces log login"},
+		{"", []string{"show", "lo"}, "log login"},
+		{"", []string{"show", "int", ""}, ""},
+		{"", []string{"show", "int", "dp0s3", ""}, "brief"},
+		{"", []string{"show", "log", ""}, "tail"},
+		{"", []string{"show", "debug", ""}, ""},
+		{"", []string{"show", "bogus", ""}, ""},
+		{"", []string{"reset", ""}, "ip ipv6"},
+		{"", []string{"reset", "ip"}, "ip ipv6"},
+		{"op", []string{"op", ""}, "reset show"},
+		{"op", []string{"op", "sh", "l"}, "log login"},
+		{"op", []string{"op", "sh", "int", "dp0s3", "b"}, "brief"},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		s := &Script{Root: testTree(), Command: test.command}
+		if err := s.WriteBash(&buf); err != nil {
+			t.Fatalf("Unexpected failure: %s\n", err)
+		}
+		got := bashComplete(t, buf.String(), test.words...)
+		if got != test.expected {
+			t.Errorf("%q: expected %q, got %q\n", test.words, test.expected, got)
+		}
+	}
+}
+
+func TestBashCommands(t *testing.T) {
+	var buf bytes.Buffer
+	s := &Script{Root: testTree()}
+	if err := s.WriteBash(&buf); err != nil {
+		t.Fatalf("Unexpected failure: %s\n", err)
+	}
+	if !strings.Contains(buf.String(), "complete -F _opcomp 'reset' 'show'\n") {
+		t.Errorf("Unexpected commands:\n%s\n", buf.String())
+	}
+}
+
+func TestZsh(t *testing.T) {
+	var buf bytes.Buffer
+	s := &Script{Root: testTree(), Command: "op-mode"}
+	if err := s.WriteZsh(&buf); err != nil {
+		t.Fatalf("Unexpected failure: %s\n", err)
+	}
+	script := buf.String()
+	for _, expected := range []string{
+		"#compdef op-mode\n",
+		"_opcomp_op_mode_words=(\n\t0 'reset show'\n",
+		"\t2 'interfaces log login'\n",
+		"'2:debug' ",
+		"\t2 'interfaces:Show interfaces\nlog:Show the log\nlogin:Show logins'\n",
+		"'ip ipv6'",
+		"'<counter>'",
+		"_opcomp_op_mode \"$@\"\n",
+	} {
+		if !strings.Contains(script, expected) {
+			t.Errorf("Expected %q in script:\n%s\n", expected, script)
+		}
+	}
+	if strings.Contains(script, "Show debugging") {
+		t.Errorf("Hidden command described:\n%s\n", script)
+	}
+}
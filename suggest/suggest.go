@@ -0,0 +1,119 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+/*
+Package suggest finds the commands a mistyped path element was most
+likely meant to be. Candidates are ranked by edit distance, counting
+a transposition of adjacent characters as a single edit, so that
+"shwo" suggests "show" and "shw" suggests "show".
+*/
+package suggest
+
+import (
+	"sort"
+)
+
+// MaxSuggestions is the most suggestions returned by Rank
+const MaxSuggestions = 3
+
+// Distance returns the number of insertions, deletions, substitutions
+// and transpositions of adjacent characters needed to turn a into b.
+func Distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	// Three rows of the optimal string alignment matrix
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = minOf(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = minOf(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(rb)]
+}
+
+func minOf(v int, vs ...int) int {
+	for _, n := range vs {
+		if n < v {
+			v = n
+		}
+	}
+	return v
+}
+
+// score is the distance from val to candidate, allowing val to be an
+// abbreviation of candidate
+func score(val, candidate string) int {
+	d := Distance(val, candidate)
+	if r := []rune(candidate); len(r) > len([]rune(val)) {
+		d = minOf(d, Distance(val, string(r[:len([]rune(val))])))
+	}
+	return d
+}
+
+// maxDistance is the furthest a candidate may be from val and still
+// be suggested
+func maxDistance(val string) int {
+	return len([]rune(val))/3 + 1
+}
+
+// Rank returns up to MaxSuggestions of candidates close enough to val
+// to be suggested, closest first. Candidates at the same distance are
+// in name order.
+func Rank(val string, candidates []string) []string {
+	type scored struct {
+		name  string
+		score int
+	}
+	limit := maxDistance(val)
+	var found []scored
+	seen := make(map[string]bool, len(candidates))
+	for _, c := range candidates {
+		if seen[c] || c == val {
+			continue
+		}
+		seen[c] = true
+		if s := score(val, c); s <= limit {
+			found = append(found, scored{name: c, score: s})
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].score != found[j].score {
+			return found[i].score < found[j].score
+		}
+		return found[i].name < found[j].name
+	})
+
+	var names []string
+	for i := 0; i < len(found) && i < MaxSuggestions; i++ {
+		names = append(names, found[i].name)
+	}
+	return names
+}
+
+// Format renders suggestions in the style of the CLI's possible
+// completions, for appending to an error message. It returns "" if
+// there are none.
+func Format(suggestions []string) string {
+	if len(suggestions) == 0 {
+		return ""
+	}
+	s := "\n\n  Did you mean:"
+	for _, name := range suggestions {
+		s += "\n  " + name
+	}
+	return s
+}
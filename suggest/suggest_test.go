@@ -0,0 +1,56 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package suggest
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b   string
+		expect int
+	}{
+		{"show", "show", 0},
+		{"shwo", "show", 1},
+		{"shw", "show", 1},
+		{"shoow", "show", 1},
+		{"shaw", "show", 1},
+		{"", "show", 4},
+		{"reset", "show", 5},
+		{"ab", "ba", 1},
+		{"ca", "abc", 3},
+	}
+
+	for _, test := range tests {
+		if got := Distance(test.a, test.b); got != test.expect {
+			t.Errorf("Distance('%s', '%s'): expected %d, got %d\n",
+				test.a, test.b, test.expect, got)
+		}
+	}
+}
+
+func TestRank(t *testing.T) {
+	candidates := []string{"show", "shutdown", "set", "reset", "ping", "interfaces"}
+
+	tests := []struct {
+		val    string
+		expect []string
+	}{
+		{"shwo", []string{"show", "shutdown"}},
+		{"shw", []string{"show", "shutdown", "set"}},
+		{"pnig", []string{"ping"}},
+		{"intrfaces", []string{"interfaces"}},
+		{"intf", []string{"interfaces"}},
+		{"xyzzy", nil},
+	}
+
+	for _, test := range tests {
+		if got := Rank(test.val, candidates); !reflect.DeepEqual(got, test.expect) {
+			t.Errorf("Rank('%s'): expected %v, got %v\n", test.val, test.expect, got)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package tmpl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseAnswer parses an answer to a confirmation prompt: yes, y or true
+// to confirm, no, n or false to decline, in any case
+func ParseAnswer(s string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "yes", "y", "true":
+		return true, nil
+	case "no", "n", "false":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid answer: %s", s)
+}
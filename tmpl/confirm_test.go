@@ -0,0 +1,39 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package tmpl
+
+import "testing"
+
+func TestParseAnswer(t *testing.T) {
+	for in, expect := range map[string]bool{
+		"y": true, "Yes": true, "true": true, " n ": false, "NO": false, "false": false,
+	} {
+		if answer, err := ParseAnswer(in); err != nil || answer != expect {
+			t.Errorf("%q: unexpected answer %t, %v\n", in, answer, err)
+		}
+	}
+	for _, in := range []string{"", "maybe", "1"} {
+		if _, err := ParseAnswer(in); err == nil {
+			t.Errorf("Expected failure for %q\n", in)
+		}
+	}
+}
+
+func TestConfirmFields(t *testing.T) {
+	tmpl := NewOpTmpl("", "", "", "reboot")
+	if _, ok := tmpl.Map()["confirm"]; ok {
+		t.Errorf("Unexpected confirm field: %v\n", tmpl.Map())
+	}
+
+	tmpl.SetConfirm("Reboot now?")
+	tmpl.SetConfirmDefault(true)
+	m := tmpl.Map()
+	if m["confirm"] != "Reboot now?" || m["confirmdefault"] != "true" {
+		t.Errorf("Unexpected fields: %v\n", m)
+	}
+	if v, _ := tmpl.GetField("confirm"); v != "Reboot now?" {
+		t.Errorf("Unexpected confirm field: %s\n", v)
+	}
+}
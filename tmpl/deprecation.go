@@ -0,0 +1,61 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package tmpl
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Deprecation describes a deprecated command found when expanding a
+// path. Deprecated commands still run, but the warning should be shown
+// to the user.
+type Deprecation struct {
+	// Path is the expanded path of the deprecated command
+	Path []string
+	// Warning is the template's warning text
+	Warning string
+	// Replacement is the path of the command to use instead, if any
+	Replacement string
+}
+
+// NewDeprecation returns the Deprecation of the command at path with
+// template t, or nil if it is not deprecated
+func NewDeprecation(path []string, t *OpTmpl) *Deprecation {
+	if t.Deprecated() == "" {
+		return nil
+	}
+	return &Deprecation{
+		Path:        append([]string(nil), path...),
+		Warning:     t.Deprecated(),
+		Replacement: t.Replacement(),
+	}
+}
+
+func (d *Deprecation) String() string {
+	s := fmt.Sprintf("Warning: '%s' is deprecated", strings.Join(d.Path, " "))
+	if w := strings.TrimSpace(d.Warning); w != "" && w != "true" {
+		s += ": " + w
+	}
+	if d.Replacement != "" {
+		s += fmt.Sprintf("\nUse '%s' instead", d.Replacement)
+	}
+	return s
+}
+
+type showHiddenKey struct{}
+
+// WithHidden returns a context in which hidden commands are listed and
+// completed as any other, as when generating documentation
+func WithHidden(ctx context.Context) context.Context {
+	return context.WithValue(ctx, showHiddenKey{}, true)
+}
+
+// ShowHidden returns true if hidden commands are listed in ctx
+func ShowHidden(ctx context.Context) bool {
+	show, _ := ctx.Value(showHiddenKey{}).(bool)
+	return show
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package tmpl
+
+import (
+	"github.com/danos/utils/pathutil"
+)
+
+// Binding associates an argument or option of a command with the value
+// the user gave for it. Index is the position of the value in the
+// invocation's path.
+type Binding struct {
+	Name   string
+	Value  string
+	Type   string
+	Secret bool
+	Index  int
+}
+
+// Invocation is a command line resolved against a command tree. It holds
+// the expanded path, the template to run and the values bound to each
+// argument and option, in the order they were given.
+type Invocation struct {
+	Path     []string
+	Template *OpTmpl
+	Bindings []Binding
+}
+
+// Binding returns the first binding with the given name
+func (i *Invocation) Binding(name string) (Binding, bool) {
+	if i == nil {
+		return Binding{}, false
+	}
+	for _, b := range i.Bindings {
+		if b.Name == name {
+			return b, true
+		}
+	}
+	return Binding{}, false
+}
+
+// PathAttrs returns the attributes of each element of the invocation's
+// path, marking the values of secret bindings
+func (i *Invocation) PathAttrs() *pathutil.PathAttrs {
+	attrs := pathutil.NewPathAttrs()
+	for range i.Path {
+		attrs.Attrs = append(attrs.Attrs, pathutil.NewPathElementAttrs())
+	}
+	for _, b := range i.Bindings {
+		if b.Secret && b.Index < len(attrs.Attrs) {
+			attrs.Attrs[b.Index].Secret = true
+		}
+	}
+	return &attrs
+}
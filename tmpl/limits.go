@@ -0,0 +1,74 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package tmpl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseTimeLimit parses a time limit given either as a whole number of
+// seconds or as a duration such as "1m30s"
+func ParseTimeLimit(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.ParseUint(s, 10, 32); err == nil {
+		return time.Duration(n) * time.Second, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid time limit: %s", s)
+	}
+	return d, nil
+}
+
+// FormatTimeLimit returns d in the form accepted by ParseTimeLimit
+func FormatTimeLimit(d time.Duration) string {
+	if d%time.Second == 0 {
+		return strconv.FormatInt(int64(d/time.Second), 10)
+	}
+	return d.String()
+}
+
+var sizeUnits = []struct {
+	suffix string
+	mult   uint64
+}{
+	{"K", 1 << 10},
+	{"M", 1 << 20},
+	{"G", 1 << 30},
+	{"T", 1 << 40},
+}
+
+// ParseSizeLimit parses a size limit in bytes, which may have a suffix
+// of K, M, G or T for binary multiples
+func ParseSizeLimit(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	num, mult := s, uint64(1)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(strings.ToUpper(s), u.suffix) {
+			num, mult = s[:len(s)-1], u.mult
+			break
+		}
+	}
+	n, err := strconv.ParseUint(num, 10, 64)
+	if err != nil || n > ^uint64(0)/mult {
+		return 0, fmt.Errorf("invalid size limit: %s", s)
+	}
+	return n * mult, nil
+}
+
+// FormatSizeLimit returns n in the form accepted by ParseSizeLimit,
+// using the largest suffix which represents it exactly
+func FormatSizeLimit(n uint64) string {
+	for i := len(sizeUnits) - 1; i >= 0; i-- {
+		u := sizeUnits[i]
+		if n != 0 && n%u.mult == 0 {
+			return strconv.FormatUint(n/u.mult, 10) + u.suffix
+		}
+	}
+	return strconv.FormatUint(n, 10)
+}
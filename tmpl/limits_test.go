@@ -0,0 +1,63 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package tmpl
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeLimits(t *testing.T) {
+	for _, tc := range []struct {
+		in, out string
+		d       time.Duration
+	}{
+		{"30", "30", 30 * time.Second},
+		{" 1m30s ", "90", 90 * time.Second},
+		{"1500ms", "1.5s", 1500 * time.Millisecond},
+		{"0", "0", 0},
+	} {
+		d, err := ParseTimeLimit(tc.in)
+		if err != nil || d != tc.d {
+			t.Errorf("Parse %q: got %v, %v\n", tc.in, d, err)
+		}
+		if s := FormatTimeLimit(d); s != tc.out {
+			t.Errorf("Format %v: got %q\n", d, s)
+		}
+	}
+
+	for _, in := range []string{"", "soon", "-5s", "10 s"} {
+		if _, err := ParseTimeLimit(in); err == nil {
+			t.Errorf("Expected failure for %q\n", in)
+		}
+	}
+}
+
+func TestSizeLimits(t *testing.T) {
+	for _, tc := range []struct {
+		in, out string
+		n       uint64
+	}{
+		{"1000", "1000", 1000},
+		{"4k", "4K", 4 << 10},
+		{"1024K", "1M", 1 << 20},
+		{"2G", "2G", 2 << 30},
+		{"0", "0", 0},
+	} {
+		n, err := ParseSizeLimit(tc.in)
+		if err != nil || n != tc.n {
+			t.Errorf("Parse %q: got %v, %v\n", tc.in, n, err)
+		}
+		if s := FormatSizeLimit(n); s != tc.out {
+			t.Errorf("Format %v: got %q\n", n, s)
+		}
+	}
+
+	for _, in := range []string{"", "K", "-1", "1.5M", "99999999999T"} {
+		if _, err := ParseSizeLimit(in); err == nil {
+			t.Errorf("Expected failure for %q\n", in)
+		}
+	}
+}
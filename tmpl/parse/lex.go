@@ -66,18 +66,36 @@ const (
 	itemLocal
 	itemFeatures
 	itemSecret
+	itemTimeout
+	itemCPULimit
+	itemMemLimit
+	itemOutputLimit
+	itemConfirm
+	itemConfirmDefault
+	itemHidden
+	itemDeprecated
+	itemReplacement
 )
 
 var key = map[string]itemType{
-	"allowed":    itemAllowed,
-	"comptype":   itemComptype,
-	"include":    itemInclude,
-	"help":       itemHelp,
-	"run":        itemRun,
-	"privileged": itemPrivileged,
-	"local":      itemLocal,
-	"features":   itemFeatures,
-	"secret":     itemSecret,
+	"allowed":        itemAllowed,
+	"comptype":       itemComptype,
+	"include":        itemInclude,
+	"help":           itemHelp,
+	"run":            itemRun,
+	"privileged":     itemPrivileged,
+	"local":          itemLocal,
+	"features":       itemFeatures,
+	"secret":         itemSecret,
+	"timeout":        itemTimeout,
+	"cpulimit":       itemCPULimit,
+	"memlimit":       itemMemLimit,
+	"outputlimit":    itemOutputLimit,
+	"confirm":        itemConfirm,
+	"confirmdefault": itemConfirmDefault,
+	"hidden":         itemHidden,
+	"deprecated":     itemDeprecated,
+	"replacement":    itemReplacement,
 }
 
 type stateFn func(*lexer) stateFn
@@ -187,7 +205,9 @@ out:
 			l.backup()
 			word := l.input[l.start:l.pos]
 			switch word {
-			case "allowed", "comptype", "help", "include", "run", "privileged", "local", "features", "secret":
+			case "allowed", "comptype", "help", "include", "run", "privileged", "local", "features", "secret",
+				"timeout", "cpulimit", "memlimit", "outputlimit", "confirm", "confirmdefault",
+				"hidden", "deprecated", "replacement":
 				l.emit(key[word])
 				/*discard the ':'*/
 				l.next()
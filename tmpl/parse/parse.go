@@ -30,6 +30,7 @@ type parser struct {
 	lex  *lexer
 	tmpl tmpl.OpTmpl
 	text string
+	err  error
 }
 
 func Parse(name, text string) (*tmpl.OpTmpl, error) {
@@ -47,6 +48,14 @@ func newParser(lex *lexer, text string) *parser {
 	return p
 }
 
+//invalid records the first field whose value could not be parsed. The
+//remaining items are still read so that the lexer can finish.
+func (p *parser) invalid(err error) {
+	if p.err == nil {
+		p.err = fmt.Errorf("%s: %s", p.lex.name, err)
+	}
+}
+
 func (p *parser) parse() error {
 	for i := range p.lex.items {
 		switch {
@@ -93,8 +102,46 @@ func (p *parser) parse() error {
 				}
 			case itemFeatures:
 				p.tmpl.SetFeatures(p.tmpl.Features() + ";" + v.val)
+			case itemTimeout:
+				if val, err := tmpl.ParseTimeLimit(v.val); err == nil {
+					p.tmpl.SetTimeout(val)
+				} else {
+					p.invalid(err)
+				}
+			case itemCPULimit:
+				if val, err := tmpl.ParseTimeLimit(v.val); err == nil {
+					p.tmpl.SetCPULimit(val)
+				} else {
+					p.invalid(err)
+				}
+			case itemMemLimit:
+				if val, err := tmpl.ParseSizeLimit(v.val); err == nil {
+					p.tmpl.SetMemLimit(val)
+				} else {
+					p.invalid(err)
+				}
+			case itemOutputLimit:
+				if val, err := tmpl.ParseSizeLimit(v.val); err == nil {
+					p.tmpl.SetOutputLimit(val)
+				} else {
+					p.invalid(err)
+				}
+			case itemConfirm:
+				p.tmpl.SetConfirm(v.val)
+			case itemConfirmDefault:
+				if val, err := tmpl.ParseAnswer(v.val); err == nil {
+					p.tmpl.SetConfirmDefault(val)
+				}
+			case itemHidden:
+				if val, err := strconv.ParseBool(v.val); err == nil {
+					p.tmpl.SetHidden(val)
+				}
+			case itemDeprecated:
+				p.tmpl.SetDeprecated(v.val)
+			case itemReplacement:
+				p.tmpl.SetReplacement(v.val)
 			}
 		}
 	}
-	return nil
+	return p.err
 }
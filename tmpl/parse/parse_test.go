@@ -0,0 +1,41 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package parse
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLimits(t *testing.T) {
+	tm, err := Parse("node.def", "help: Show log\ntimeout: 30\n"+
+		"cpulimit: 1500ms\nmemlimit: 64M\noutputlimit: 1k\nrun: show-log\n")
+	if err != nil {
+		t.Fatalf("Unexpected parse failure: %s\n", err)
+	}
+	if tm.Timeout() != 30*time.Second || tm.CPULimit() != 1500*time.Millisecond ||
+		tm.MemLimit() == 0 || tm.OutputLimit() == 0 || tm.Run() != "show-log" {
+		t.Errorf("Unexpected template: %v\n", tm.Map())
+	}
+}
+
+func TestParseInvalidLimits(t *testing.T) {
+	for _, field := range []string{
+		"timeout: soon",
+		"cpulimit: -5s",
+		"memlimit: lots",
+		"outputlimit: 10 parsecs",
+	} {
+		_, err := Parse("node.def", "help: Show log\n"+field+"\nrun: show-log\n")
+		if err == nil {
+			t.Errorf("Expected failure parsing %q\n", field)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), "node.def: ") {
+			t.Errorf("Error does not name the template: %s\n", err)
+		}
+	}
+}
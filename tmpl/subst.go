@@ -0,0 +1,719 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package tmpl
+
+import (
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ShellQuote returns s quoted so the shell treats it as a single word
+// with no expansion
+func ShellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// escapeDouble escapes s for use within double quotes
+func escapeDouble(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '"', '\\', '$', '`':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// escapeHeredoc escapes s for use in the body of a here-document
+func escapeHeredoc(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '\\', '$', '`':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// quoting is the shell quoting context at a point in a run string
+type quoting int
+
+const (
+	unquoted quoting = iota
+	singleQuoted
+	doubleQuoted
+	// heredocBody is the body of a here-document with an unquoted
+	// delimiter, which is expanded as if double quoted but in which
+	// quotes are not special
+	heredocBody
+)
+
+// argRef is a reference to arguments found in a run string
+type argRef struct {
+	start, end int
+	text       string
+	// all is set for $@, $* and ${@:n}, which may expand to any
+	// number of words; join is set for $*
+	all  bool
+	join bool
+	// first and count select the positional arguments, 1 based;
+	// count < 0 means all remaining
+	first, count int
+	name         string
+}
+
+// parseRef parses the argument reference, if any, starting with the
+// '$' at s[i]. bindings are the names usable in ${name}.
+func parseRef(s string, i int, named func(string) bool) (argRef, bool) {
+	ref := argRef{start: i, count: 1}
+	if i+1 >= len(s) {
+		return ref, false
+	}
+	switch c := s[i+1]; {
+	case c >= '1' && c <= '9':
+		ref.end = i + 2
+		ref.first = int(c - '0')
+	case c == '@' || c == '*':
+		ref.end = i + 2
+		ref.all, ref.join, ref.first, ref.count = true, c == '*', 1, -1
+	case c == '{':
+		close := strings.IndexByte(s[i:], '}')
+		if close < 0 {
+			return ref, false
+		}
+		ref.end = i + close + 1
+		if !parseBraced(s[i+2:i+close], &ref, named) {
+			return ref, false
+		}
+	default:
+		return ref, false
+	}
+	ref.text = s[ref.start:ref.end]
+	return ref, true
+}
+
+func parseBraced(body string, ref *argRef, named func(string) bool) bool {
+	if n, err := strconv.Atoi(body); err == nil && n > 0 {
+		ref.first = n
+		return true
+	}
+	if body == "@" || body == "*" {
+		ref.all, ref.join, ref.first, ref.count = true, body == "*", 1, -1
+		return true
+	}
+	if strings.HasPrefix(body, "@:") || strings.HasPrefix(body, "*:") {
+		parts := strings.Split(body[2:], ":")
+		if len(parts) > 2 {
+			return false
+		}
+		first, err := strconv.Atoi(parts[0])
+		if err != nil || first < 1 {
+			return false
+		}
+		count := -1
+		if len(parts) == 2 {
+			if count, err = strconv.Atoi(parts[1]); err != nil || count < 0 {
+				return false
+			}
+		}
+		ref.all, ref.join, ref.first, ref.count = true, body[0] == '*', first, count
+		return true
+	}
+	if named(body) {
+		ref.name = body
+		return true
+	}
+	return false
+}
+
+// values returns the words ref expands to for inv
+func (ref argRef) values(inv *Invocation) []string {
+	if ref.name != "" {
+		b, _ := inv.Binding(ref.name)
+		return []string{b.Value}
+	}
+	var vals []string
+	for n := ref.first; ref.count < 0 || n < ref.first+ref.count; n++ {
+		if n > len(inv.Path) {
+			break
+		}
+		vals = append(vals, inv.Path[n-1])
+	}
+	if !ref.all && len(vals) == 0 {
+		return []string{""}
+	}
+	return vals
+}
+
+// quoteFor returns s quoted so that a shell parsing it in context q
+// takes it literally
+func quoteFor(s string, q quoting) string {
+	switch q {
+	case singleQuoted:
+		// Close the quotes around each quote of s
+		return strings.Replace(s, "'", `'\''`, -1)
+	case doubleQuoted:
+		return escapeDouble(s)
+	case heredocBody:
+		return escapeHeredoc(s)
+	}
+	return ShellQuote(s)
+}
+
+// quote returns vals quoted for context q. The values of $@ remain
+// separate words, except in a here-document.
+func (ref argRef) quote(vals []string, q quoting) string {
+	if ref.join {
+		return quoteFor(strings.Join(vals, " "), q)
+	}
+	quoted := make([]string, len(vals))
+	for i, v := range vals {
+		quoted[i] = quoteFor(v, q)
+	}
+	switch q {
+	case singleQuoted:
+		return strings.Join(quoted, `' '`)
+	case doubleQuoted:
+		return strings.Join(quoted, `" "`)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// render returns the text replacing ref, quoted for context ctx. Where
+// the text is parsed a second time, as by eval, the values are quoted
+// for the quoting they are parsed with then, and the result quoted
+// again for the run string itself.
+func (ref argRef) render(vals []string, ctx refContext) string {
+	if ctx.reparse == notReparsed {
+		return ref.quote(vals, ctx.quoting)
+	}
+	return quoteFor(ref.quote(vals, ctx.inner), ctx.quoting)
+}
+
+// reparseKind identifies how an argument of a command is parsed again
+// as shell commands
+type reparseKind int
+
+const (
+	notReparsed reparseKind = iota
+	// reparseEval is an argument of eval
+	reparseEval
+	// reparseShell is the command string of sh -c and the like, or the
+	// remote command of ssh
+	reparseShell
+)
+
+// shells are the commands whose -c option takes a command string
+var shells = map[string]bool{"sh": true, "bash": true, "dash": true, "su": true}
+
+// sshArgOpts are the ssh options which take an argument
+const sshArgOpts = "BbcDEeFIiJLlmOopQRSWw"
+
+// reparsedArg returns how the argument following words, the words of a
+// simple command so far, is parsed again, if at all
+func reparsedArg(words []string) reparseKind {
+	if len(words) == 0 {
+		return notReparsed
+	}
+	switch name := path.Base(words[0]); {
+	case name == "eval":
+		return reparseEval
+	case shells[name]:
+		prev := words[len(words)-1]
+		if len(words) > 1 && (prev == "--command" ||
+			(strings.HasPrefix(prev, "-") && !strings.HasPrefix(prev, "--") &&
+				strings.ContainsRune(prev, 'c'))) {
+			return reparseShell
+		}
+	case name == "ssh":
+		for i := 1; i < len(words); i++ {
+			w := words[i]
+			if !strings.HasPrefix(w, "-") {
+				// w is the host, and the words following it the command
+				return reparseShell
+			}
+			if len(w) == 2 && strings.ContainsRune(sshArgOpts, rune(w[1])) {
+				i++
+			}
+		}
+	}
+	return notReparsed
+}
+
+// reservedWords may precede the name of a simple command
+var reservedWords = map[string]bool{
+	"!": true, "{": true, "if": true, "then": true, "else": true,
+	"elif": true, "while": true, "until": true, "do": true, "time": true,
+}
+
+// isAssignment returns true if w assigns a variable
+func isAssignment(w string) bool {
+	i := strings.IndexByte(w, '=')
+	if i <= 0 {
+		return false
+	}
+	for j, c := range w[:i] {
+		if !(c == '_' || unicode.IsLetter(c) || (j > 0 && unicode.IsDigit(c))) {
+			return false
+		}
+	}
+	return true
+}
+
+// innerQuoting tracks the quoting of text as a command parsing it again
+// sees it
+type innerQuoting struct {
+	q       quoting
+	escaped bool
+}
+
+func (r *innerQuoting) feed(c byte) {
+	if r.escaped {
+		r.escaped = false
+		return
+	}
+	switch r.q {
+	case unquoted:
+		switch c {
+		case '\\':
+			r.escaped = true
+		case '\'':
+			r.q = singleQuoted
+		case '"':
+			r.q = doubleQuoted
+		}
+	case singleQuoted:
+		if c == '\'' {
+			r.q = unquoted
+		}
+	case doubleQuoted:
+		switch c {
+		case '\\':
+			r.escaped = true
+		case '"':
+			r.q = unquoted
+		}
+	}
+}
+
+// shellCommand is the simple command being parsed, by the run string's
+// shell or one running a command substitution
+type shellCommand struct {
+	words  []string
+	word   strings.Builder // the current word, with quoting removed
+	inWord bool
+	parens int
+	// reparse is how the current word is parsed again, with inner the
+	// quoting it is parsed with
+	reparse reparseKind
+	inner   innerQuoting
+}
+
+func (c *shellCommand) startWord() {
+	if c.inWord {
+		return
+	}
+	c.inWord = true
+	kind := reparsedArg(c.words)
+	if kind != c.reparse {
+		c.inner = innerQuoting{}
+	}
+	c.reparse = kind
+}
+
+// add adds b, as it remains once the shell has removed quoting, to the
+// current word
+func (c *shellCommand) add(b byte) {
+	c.startWord()
+	c.word.WriteByte(b)
+	if c.reparse != notReparsed {
+		c.inner.feed(b)
+	}
+}
+
+func (c *shellCommand) endWord() {
+	if !c.inWord {
+		return
+	}
+	w := c.word.String()
+	if len(c.words) > 0 || !(reservedWords[w] || isAssignment(w)) {
+		c.words = append(c.words, w)
+	}
+	if c.reparse != notReparsed {
+		// The words are joined to be parsed together
+		c.inner.feed(' ')
+	}
+	c.word.Reset()
+	c.inWord = false
+}
+
+func (c *shellCommand) endCommand() {
+	c.endWord()
+	c.words = nil
+	c.reparse = notReparsed
+}
+
+// heredoc is a here-document whose body is yet to be read
+type heredoc struct {
+	delim     string
+	stripTabs bool
+	// quoted is set if any of the delimiter is quoted, so that the
+	// body is not expanded
+	quoted    bool
+	lineStart bool
+}
+
+// parseHeredoc parses the delimiter of a here-document whose operator
+// ends before s[i], returning the here-document and the end of the
+// delimiter
+func parseHeredoc(s string, i int) (*heredoc, int) {
+	h := &heredoc{lineStart: true}
+	if i < len(s) && s[i] == '-' {
+		h.stripTabs = true
+		i++
+	}
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+		i++
+	}
+	var delim strings.Builder
+	for ; i < len(s) && !strings.ContainsRune(" \t\n;&|<>()", rune(s[i])); i++ {
+		switch c := s[i]; c {
+		case '\\':
+			h.quoted = true
+			if i+1 < len(s) {
+				i++
+				delim.WriteByte(s[i])
+			}
+		case '\'', '"':
+			h.quoted = true
+			end := strings.IndexByte(s[i+1:], c)
+			if end < 0 {
+				end = len(s) - i - 1
+			}
+			delim.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+		default:
+			delim.WriteByte(c)
+		}
+	}
+	h.delim = delim.String()
+	return h, i
+}
+
+// scanLevel is a quoting context of a run string. A command
+// substitution starts a new command; other contexts continue that of
+// the level below.
+type scanLevel struct {
+	q   quoting
+	cmd *shellCommand
+	// closer ends a command substitution
+	closer byte
+	doc    *heredoc
+}
+
+// refContext describes where an argument reference appears
+type refContext struct {
+	quoting quoting
+	cmdSub  bool
+	// reparse is set where the text of the reference is parsed again,
+	// with inner the quoting it is then parsed with
+	reparse reparseKind
+	inner   quoting
+}
+
+// scanRefs calls fn for each argument reference in s, in order, with
+// the context it appears in. Single quoted text and the bodies of
+// here-documents with quoted delimiters are never scanned.
+func scanRefs(s string, named func(string) bool, fn func(argRef, refContext)) {
+	stack := []scanLevel{{q: unquoted, cmd: &shellCommand{}}}
+	var pending []*heredoc
+
+	push := func(l scanLevel) { stack = append(stack, l) }
+	pop := func() { stack = stack[:len(stack)-1] }
+	cmdSub := func(closer byte) {
+		if cmd := stack[len(stack)-1].cmd; cmd != nil {
+			cmd.startWord()
+		}
+		push(scanLevel{q: unquoted, cmd: &shellCommand{}, closer: closer})
+	}
+	context := func() refContext {
+		ctx := refContext{quoting: stack[len(stack)-1].q}
+		for i := len(stack) - 1; i >= 0; i-- {
+			l := stack[i]
+			if l.closer != 0 {
+				ctx.cmdSub = true
+			}
+			if ctx.reparse == notReparsed && l.cmd != nil && l.cmd.inWord &&
+				l.cmd.reparse != notReparsed {
+				ctx.reparse, ctx.inner = l.cmd.reparse, l.cmd.inner.q
+			}
+		}
+		return ctx
+	}
+	// dollar handles the '$' at s[i], returning the index of the last
+	// byte consumed
+	dollar := func(i int) int {
+		l := stack[len(stack)-1]
+		if i+1 < len(s) && s[i+1] == '(' {
+			cmdSub(')')
+			return i + 1
+		}
+		ref, ok := parseRef(s, i, named)
+		if !ok {
+			if l.cmd != nil {
+				l.cmd.add('$')
+			}
+			return i
+		}
+		if l.cmd != nil {
+			l.cmd.startWord()
+		}
+		fn(ref, context())
+		if l.cmd != nil {
+			l.cmd.word.WriteString(ref.text)
+		}
+		return ref.end - 1
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		l := stack[len(stack)-1]
+		cmd := l.cmd
+		switch l.q {
+		case singleQuoted:
+			if c == '\'' {
+				pop()
+			} else {
+				cmd.add(c)
+			}
+			continue
+		case doubleQuoted:
+			switch c {
+			case '\\':
+				if i+1 < len(s) && strings.IndexByte("$`\"\\\n", s[i+1]) >= 0 {
+					i++
+					if s[i] != '\n' {
+						cmd.add(s[i])
+					}
+				} else {
+					cmd.add(c)
+				}
+			case '"':
+				pop()
+			case '`':
+				cmdSub('`')
+			case '$':
+				i = dollar(i)
+			default:
+				cmd.add(c)
+			}
+			continue
+		case heredocBody:
+			doc := l.doc
+			if doc.lineStart {
+				end := strings.IndexByte(s[i:], '\n')
+				if end < 0 {
+					end = len(s) - i
+				}
+				line := s[i : i+end]
+				if doc.stripTabs {
+					line = strings.TrimLeft(line, "\t")
+				}
+				if line == doc.delim {
+					pop()
+					i += end
+					continue
+				}
+				doc.lineStart = false
+			}
+			switch {
+			case c == '\n':
+				doc.lineStart = true
+			case doc.quoted:
+			case c == '\\':
+				i++
+			case c == '`':
+				cmdSub('`')
+			case c == '$':
+				i = dollar(i)
+			}
+			continue
+		}
+
+		switch c {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				if s[i] != '\n' {
+					cmd.add(s[i])
+				}
+			}
+		case '\'':
+			cmd.startWord()
+			push(scanLevel{q: singleQuoted, cmd: cmd})
+		case '"':
+			cmd.startWord()
+			push(scanLevel{q: doubleQuoted, cmd: cmd})
+		case '`':
+			if l.closer == '`' {
+				cmd.endCommand()
+				pop()
+			} else {
+				cmdSub('`')
+			}
+		case '(':
+			cmd.endCommand()
+			cmd.parens++
+		case ')':
+			cmd.endCommand()
+			if cmd.parens > 0 {
+				cmd.parens--
+			} else if l.closer == ')' {
+				pop()
+			}
+		case ' ', '\t':
+			cmd.endWord()
+		case '\n':
+			cmd.endCommand()
+			// Here-documents are read in turn from the next line
+			for j := len(pending) - 1; j >= 0; j-- {
+				push(scanLevel{q: heredocBody, doc: pending[j]})
+			}
+			pending = nil
+		case ';', '&', '|':
+			cmd.endCommand()
+		case '<':
+			cmd.endWord()
+			if i+1 < len(s) && s[i+1] == '<' {
+				if i+2 < len(s) && s[i+2] == '<' {
+					// A here-string is an ordinary word
+					i += 2
+					continue
+				}
+				var doc *heredoc
+				doc, i = parseHeredoc(s, i+2)
+				pending = append(pending, doc)
+				i--
+			}
+		case '>':
+			cmd.endWord()
+		case '$':
+			i = dollar(i)
+		default:
+			cmd.add(c)
+		}
+	}
+}
+
+func bindingNames(inv *Invocation) func(string) bool {
+	return func(name string) bool {
+		_, ok := inv.Binding(name)
+		return ok
+	}
+}
+
+// Substitute expands the argument references in s with the values
+// from inv, quoting each for the shell so that no value is subject to
+// further expansion. Values in the arguments of eval, in the command
+// strings of sh -c and the like and in the remote command of ssh are
+// quoted twice, as those are parsed again. References are positional, $1 to $9 or ${n} for
+// the n'th element of the path, all elements as $@, $* and ${@:n} or
+// ${@:n:count}, or named as ${name} for a binding. Other uses of '$'
+// are left to the shell.
+func Substitute(s string, inv *Invocation) string {
+	var b strings.Builder
+	last := 0
+	scanRefs(s, bindingNames(inv), func(ref argRef, ctx refContext) {
+		b.WriteString(s[last:ref.start])
+		b.WriteString(ref.render(ref.values(inv), ctx))
+		last = ref.end
+	})
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// Run returns the template's run string with argument references
+// substituted
+func (i *Invocation) Run() string {
+	return Substitute(i.Template.Run(), i)
+}
+
+// Allowed returns the template's allowed string with argument
+// references substituted
+func (i *Invocation) Allowed() string {
+	return Substitute(i.Template.Allowed(), i)
+}
+
+// ArgUsage identifies a reason a use of arguments is unsafe
+type ArgUsage int
+
+const (
+	// UsageUnquoted is an expansion outside double quotes, which is
+	// subject to word splitting and globbing
+	UsageUnquoted ArgUsage = iota
+	// UsageEval is an expansion in the arguments of eval, which are
+	// parsed again as shell commands
+	UsageEval
+	// UsageCommandSubst is an expansion within command substitution
+	UsageCommandSubst
+	// UsageShellCommand is an expansion in the command string of sh -c
+	// and the like, or the remote command of ssh, which are parsed
+	// again as shell commands
+	UsageShellCommand
+)
+
+func (u ArgUsage) String() string {
+	switch u {
+	case UsageUnquoted:
+		return "unquoted"
+	case UsageEval:
+		return "eval"
+	case UsageCommandSubst:
+		return "command substitution"
+	case UsageShellCommand:
+		return "shell command"
+	}
+	return "unknown"
+}
+
+// ArgFinding is an unsafe use of arguments in a run string. Offset is
+// the position of the reference in the string.
+type ArgFinding struct {
+	Offset int
+	Ref    string
+	Usage  ArgUsage
+}
+
+// AnalyseArgs reports the argument references in s which, if s were
+// run with the arguments as positional parameters, could allow a
+// value to be interpreted by the shell. Named references are not
+// recognised, as the shell itself does not.
+func AnalyseArgs(s string) []ArgFinding {
+	var findings []ArgFinding
+	none := func(string) bool { return false }
+	scanRefs(s, none, func(ref argRef, ctx refContext) {
+		f := ArgFinding{Offset: ref.start, Ref: ref.text}
+		switch {
+		case ctx.reparse == reparseEval:
+			f.Usage = UsageEval
+		case ctx.reparse == reparseShell:
+			f.Usage = UsageShellCommand
+		case ctx.cmdSub && ctx.quoting == unquoted:
+			f.Usage = UsageCommandSubst
+		case ctx.quoting == unquoted:
+			f.Usage = UsageUnquoted
+		default:
+			return
+		}
+		findings = append(findings, f)
+	})
+	return findings
+}
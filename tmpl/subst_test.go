@@ -0,0 +1,181 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package tmpl
+
+import (
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testInvocation() *Invocation {
+	return &Invocation{
+		Path: []string{"show", "user", "it's; rm -rf /", "log", "a b", "$HOME"},
+		Bindings: []Binding{
+			{Name: "user", Value: "it's; rm -rf /", Index: 2},
+		},
+	}
+}
+
+func TestSubstitute(t *testing.T) {
+	inv := testInvocation()
+
+	tests := []struct {
+		run    string
+		expect string
+	}{
+		{`echo $1`, `echo 'show'`},
+		{`echo $3`, `echo 'it'\''s; rm -rf /'`},
+		{`echo "$3"`, `echo "it's; rm -rf /"`},
+		{`echo "${6}"`, `echo "\$HOME"`},
+		{`echo ${@:5}`, `echo 'a b' '$HOME'`},
+		{`echo "${@:4:2}"`, `echo "log" "a b"`},
+		{`echo "$*"`, `echo "show user it's; rm -rf / log a b \$HOME"`},
+		{`echo ${user}`, `echo 'it'\''s; rm -rf /'`},
+		{`echo ${other} $HOME $#`, `echo ${other} $HOME $#`},
+		{`echo '$1' \$1`, `echo '$1' \$1`},
+		{`echo $9`, `echo ''`},
+		{`echo $(basename "$2")`, `echo $(basename "user")`},
+		{`eval echo $2`, `eval echo ''\''user'\'''`},
+		{`eval "echo $2"`, `eval "echo 'user'"`},
+		{`eval "echo $6"`, `eval "echo '\$HOME'"`},
+		{`eval echo $2; echo $1`, `eval echo ''\''user'\'''; echo 'show'`},
+	}
+
+	for _, test := range tests {
+		if got := Substitute(test.run, inv); got != test.expect {
+			t.Errorf("Substituting %s:\n Expected - %s\n Got - %s\n",
+				test.run, test.expect, got)
+		}
+	}
+}
+
+func TestSubstituteShell(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no shell")
+	}
+	inv := testInvocation()
+
+	run := Substitute(`printf '%s|' $3 "$5" ${@:5}`, inv)
+	out, err := exec.Command("sh", "-c", run).Output()
+	if err != nil {
+		t.Fatalf("Unexpected failure running %s: %s\n", run, err)
+	}
+	expect := "it's; rm -rf /|a b|a b|$HOME|"
+	if string(out) != expect {
+		t.Errorf("Unexpected output from %s:\n Expected - %s\n Got - %s\n",
+			run, expect, out)
+	}
+}
+
+func TestSubstituteEval(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no shell")
+	}
+	// Values which would run a command if parsed again
+	inv := &Invocation{
+		Path: []string{"show", "it's; echo x", "a b", "$(echo x)"},
+	}
+
+	expect := "it's; echo x|a b|$(echo x)|"
+	for _, run := range []string{
+		`eval printf "'%s|'" $2 "$3" $4`,
+		`eval "printf '%s|' $2 $3 $4"`,
+		`eval printf "'%s|'" "${@:2:1}" ${@:3}`,
+	} {
+		sub := Substitute(run, inv)
+		out, err := exec.Command("sh", "-c", sub).Output()
+		if err != nil {
+			t.Errorf("Unexpected failure running %s: %s\n", sub, err)
+			continue
+		}
+		if string(out) != expect {
+			t.Errorf("Unexpected output from %s:\n Expected - %s\n Got - %s\n",
+				sub, expect, out)
+		}
+	}
+}
+
+func TestSubstituteReparsed(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no shell")
+	}
+	// A value which would run a command if expanded again
+	payload := "$(echo PWNED >&2)"
+	inv := &Invocation{Path: []string{"show", payload}}
+
+	tests := []struct {
+		run    string
+		expect string
+	}{
+		{`eval "echo \"$2\""`, payload},
+		{`eval "echo '$2'"`, payload},
+		{"cat <<EOF\n$2\nEOF", payload},
+		{"cat <<-EOF\n\t$2 \"$2\" '$2'\n\tEOF", payload + ` "` + payload + `" '` + payload + `'`},
+		{"cat <<EOF; echo $2\n$2\nEOF", payload + "\n" + payload},
+		{"cat <<'EOF'\n$2\nEOF", "$2"},
+		{`sh -c "echo $2"`, payload},
+		{`sh -c "echo \"$2\""`, payload},
+		{`sh -ec 'echo '"$2"`, payload},
+		{`sh -c 'echo "$1"' sh "$2"`, payload},
+	}
+
+	for _, test := range tests {
+		sub := Substitute(test.run, inv)
+		cmd := exec.Command("sh", "-c", sub)
+		var stderr strings.Builder
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			t.Errorf("Unexpected failure running %s: %s\n", sub, err)
+			continue
+		}
+		if stderr.Len() != 0 {
+			t.Errorf("Running %s wrote to stderr: %s\n", sub, stderr.String())
+		}
+		if got := strings.TrimSuffix(string(out), "\n"); got != test.expect {
+			t.Errorf("Unexpected output from %s:\n Expected - %s\n Got - %s\n",
+				sub, test.expect, got)
+		}
+	}
+}
+
+func TestAnalyseArgs(t *testing.T) {
+	tests := []struct {
+		run    string
+		expect []ArgUsage
+	}{
+		{`show-interface "$4"`, nil},
+		{`show-interface $4`, []ArgUsage{UsageUnquoted}},
+		{`show-interface "${@:4}" | grep $5`, []ArgUsage{UsageUnquoted}},
+		{`eval "show $4"`, []ArgUsage{UsageEval}},
+		{`eval "show $4"; echo "$5"`, []ArgUsage{UsageEval}},
+		{`x=$(cat $3); echo "$x"`, []ArgUsage{UsageCommandSubst}},
+		{`echo "$(cat "$3")"`, nil},
+		{`echo '$3'`, nil},
+		{`eval "echo \"$2\""`, []ArgUsage{UsageEval}},
+		{`sh -c "echo $2"`, []ArgUsage{UsageShellCommand}},
+		{`bash -c "echo $2"`, []ArgUsage{UsageShellCommand}},
+		{`su -c "echo $2" admin`, []ArgUsage{UsageShellCommand}},
+		{`ssh -p 22 host "cat $2"`, []ArgUsage{UsageShellCommand}},
+		{`sh -c 'echo "$1"' sh "$2"`, nil},
+		{"cat <<EOF\n$2\nEOF", nil},
+	}
+
+	for _, test := range tests {
+		var got []ArgUsage
+		for _, f := range AnalyseArgs(test.run) {
+			got = append(got, f.Usage)
+			if !strings.HasPrefix(test.run[f.Offset:], f.Ref) {
+				t.Errorf("Finding %v does not locate its reference in %s\n", f, test.run)
+			}
+		}
+		if !reflect.DeepEqual(got, test.expect) {
+			t.Errorf("Analysing %s:\n Expected - %v\n Got - %v\n",
+				test.run, test.expect, got)
+		}
+	}
+}
@@ -11,6 +11,7 @@ package tmpl
 import (
 	"fmt"
 	"strconv"
+	"time"
 )
 
 //OpTmpl represents an operational mode template
@@ -26,6 +27,15 @@ type OpTmpl struct {
 	secret      bool
 	yang        bool
 	passOpcArgs bool
+	timeout     time.Duration
+	cpuLimit    time.Duration
+	memLimit    uint64
+	outputLimit uint64
+	confirm     string
+	confirmDef  bool
+	hidden      bool
+	deprecated  string
+	replacement string
 }
 
 //NewOpTmpl creates a new operational template with the provided field values
@@ -65,6 +75,24 @@ func (t *OpTmpl) GetField(name string) (string, error) {
 		return strconv.FormatBool(t.Local()), nil
 	case "secret":
 		return strconv.FormatBool(t.Secret()), nil
+	case "timeout":
+		return FormatTimeLimit(t.Timeout()), nil
+	case "cpulimit":
+		return FormatTimeLimit(t.CPULimit()), nil
+	case "memlimit":
+		return FormatSizeLimit(t.MemLimit()), nil
+	case "outputlimit":
+		return FormatSizeLimit(t.OutputLimit()), nil
+	case "confirm":
+		return t.Confirm(), nil
+	case "confirmdefault":
+		return strconv.FormatBool(t.ConfirmDefault()), nil
+	case "hidden":
+		return strconv.FormatBool(t.Hidden()), nil
+	case "deprecated":
+		return t.Deprecated(), nil
+	case "replacement":
+		return t.Replacement(), nil
 	}
 	return "", fmt.Errorf("invalid field: %s", name)
 }
@@ -227,6 +255,153 @@ func (t *OpTmpl) SetPassOpcArgs(v bool) {
 	t.passOpcArgs = v
 }
 
+//Timeout returns the wall-clock time the command may run for, 0 if unlimited
+func (t *OpTmpl) Timeout() time.Duration {
+	if t == nil {
+		return 0
+	}
+	return t.timeout
+}
+
+//SetTimeout overwrites the timeout field of the template
+func (t *OpTmpl) SetTimeout(v time.Duration) {
+	if t == nil {
+		return
+	}
+	t.timeout = v
+}
+
+//CPULimit returns the CPU time the command may use, 0 if unlimited
+func (t *OpTmpl) CPULimit() time.Duration {
+	if t == nil {
+		return 0
+	}
+	return t.cpuLimit
+}
+
+//SetCPULimit overwrites the cpulimit field of the template
+func (t *OpTmpl) SetCPULimit(v time.Duration) {
+	if t == nil {
+		return
+	}
+	t.cpuLimit = v
+}
+
+//MemLimit returns the address space, in bytes, the command may use, 0 if unlimited
+func (t *OpTmpl) MemLimit() uint64 {
+	if t == nil {
+		return 0
+	}
+	return t.memLimit
+}
+
+//SetMemLimit overwrites the memlimit field of the template
+func (t *OpTmpl) SetMemLimit(v uint64) {
+	if t == nil {
+		return
+	}
+	t.memLimit = v
+}
+
+//OutputLimit returns the output, in bytes, the command may produce, 0 if unlimited
+func (t *OpTmpl) OutputLimit() uint64 {
+	if t == nil {
+		return 0
+	}
+	return t.outputLimit
+}
+
+//SetOutputLimit overwrites the outputlimit field of the template
+func (t *OpTmpl) SetOutputLimit(v uint64) {
+	if t == nil {
+		return
+	}
+	t.outputLimit = v
+}
+
+//Confirm returns the prompt to confirm the command with before it is run,
+//"" if it runs without confirmation
+func (t *OpTmpl) Confirm() string {
+	if t == nil {
+		return ""
+	}
+	return t.confirm
+}
+
+//SetConfirm overwrites the confirm field of the template
+func (t *OpTmpl) SetConfirm(v string) {
+	if t == nil {
+		return
+	}
+	t.confirm = v
+}
+
+//ConfirmDefault returns the answer to the confirmation prompt assumed when
+//none is given
+func (t *OpTmpl) ConfirmDefault() bool {
+	if t == nil {
+		return false
+	}
+	return t.confirmDef
+}
+
+//SetConfirmDefault overwrites the confirmdefault field of the template
+func (t *OpTmpl) SetConfirmDefault(v bool) {
+	if t == nil {
+		return
+	}
+	t.confirmDef = v
+}
+
+//Hidden returns true if the command is left out of completions and listings
+func (t *OpTmpl) Hidden() bool {
+	if t == nil {
+		return false
+	}
+	return t.hidden
+}
+
+//SetHidden overwrites the hidden field of the template
+func (t *OpTmpl) SetHidden(v bool) {
+	if t == nil {
+		return
+	}
+	t.hidden = v
+}
+
+//Deprecated returns the warning given when the command is used, "" if it is
+//not deprecated
+func (t *OpTmpl) Deprecated() string {
+	if t == nil {
+		return ""
+	}
+	return t.deprecated
+}
+
+//SetDeprecated overwrites the deprecated field of the template
+func (t *OpTmpl) SetDeprecated(v string) {
+	if t == nil {
+		return
+	}
+	t.deprecated = v
+}
+
+//Replacement returns the path of the command replacing a deprecated command
+func (t *OpTmpl) Replacement() string {
+	if t == nil {
+		return ""
+	}
+	return t.replacement
+}
+
+//SetReplacement overwrites the replacement field of the template
+func (t *OpTmpl) SetReplacement(v string) {
+	if t == nil {
+		return
+	}
+	t.replacement = v
+}
+
 func (t *OpTmpl) Yang() bool {
 	if t == nil {
 		return false
@@ -255,5 +430,30 @@ func (t *OpTmpl) Map() map[string]string {
 	tmap["run"] = t.run
 	tmap["privileged"] = strconv.FormatBool(t.priv)
 	tmap["local"] = strconv.FormatBool(t.local)
+	if t.timeout != 0 {
+		tmap["timeout"] = FormatTimeLimit(t.timeout)
+	}
+	if t.cpuLimit != 0 {
+		tmap["cpulimit"] = FormatTimeLimit(t.cpuLimit)
+	}
+	if t.memLimit != 0 {
+		tmap["memlimit"] = FormatSizeLimit(t.memLimit)
+	}
+	if t.outputLimit != 0 {
+		tmap["outputlimit"] = FormatSizeLimit(t.outputLimit)
+	}
+	if t.confirm != "" {
+		tmap["confirm"] = t.confirm
+		tmap["confirmdefault"] = strconv.FormatBool(t.confirmDef)
+	}
+	if t.hidden {
+		tmap["hidden"] = strconv.FormatBool(t.hidden)
+	}
+	if t.deprecated != "" {
+		tmap["deprecated"] = t.deprecated
+	}
+	if t.replacement != "" {
+		tmap["replacement"] = t.replacement
+	}
 	return tmap
 }
@@ -0,0 +1,80 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package tree
+
+import (
+	"context"
+
+	"github.com/danos/op/auth"
+)
+
+//cache returns an authoriser remembering the decisions of a for the
+//lifetime of a single request, or nil if a is nil.
+func cache(a auth.Authoriser) auth.Authoriser {
+	if a == nil {
+		return nil
+	}
+	return auth.NewCache(a)
+}
+
+//permitted returns those of chs, children of the expanded path p, which a
+//permits the user carried by ctx to use for op. Tag nodes are kept, their
+//values being authorised as part of the paths they lead to. A nil a
+//permits everything.
+func permitted(
+	ctx context.Context,
+	a auth.Authoriser,
+	op auth.Operation,
+	p Path,
+	chs []*OpTree,
+) ([]*OpTree, error) {
+	if a == nil {
+		return chs, nil
+	}
+	var names []string
+	for _, c := range chs {
+		if c.Name() != "node.tag" {
+			names = append(names, c.Name())
+		}
+	}
+	if len(names) == 0 {
+		return chs, nil
+	}
+
+	user, _ := auth.UserFromContext(ctx)
+	apath := auth.NewPath(p...)
+	ds, err := auth.AuthoriseChildren(ctx, a,
+		auth.Request{User: user, Op: op, Path: apath}, names)
+	if err != nil {
+		return nil, &auth.Error{Path: apath, Err: err}
+	}
+	var permits []*OpTree
+	for _, c := range chs {
+		if c.Name() == "node.tag" || ds[c.Name()].Permit {
+			permits = append(permits, c)
+		}
+	}
+	return permits, nil
+}
+
+//authorised returns true if a permits the user carried by ctx to use the
+//expanded path p for op. A nil a permits everything.
+func authorised(
+	ctx context.Context,
+	a auth.Authoriser,
+	op auth.Operation,
+	p Path,
+) (bool, error) {
+	if a == nil {
+		return true, nil
+	}
+	user, _ := auth.UserFromContext(ctx)
+	apath := auth.NewPath(p...)
+	d, err := a.Authorise(ctx, auth.Request{User: user, Op: op, Path: apath})
+	if err != nil {
+		return false, &auth.Error{Path: apath, Err: err}
+	}
+	return d.Permit, nil
+}
@@ -84,11 +84,19 @@ func processIncludes(o *OpTree, idata []includedata) error {
 }
 
 func BuildOpTree(path string) (*OpTree, error) {
+	return BuildOpTreeFeatures(path, capsLocation, systemCapsLocation)
+}
+
+// BuildOpTreeFeatures builds the tree with the features enabled in the
+// given feature directories, rather than those of the running system,
+// as when describing the commands of another system.
+func BuildOpTreeFeatures(path string, featureDirs ...string) (*OpTree, error) {
 	var idata = make([]includedata, 0, 10)
 	var caps = make(map[string]bool)
 
-	getSystemCapabilities(capsLocation, caps)
-	getSystemCapabilities(systemCapsLocation, caps)
+	for _, dir := range featureDirs {
+		getSystemCapabilities(dir, caps)
+	}
 
 	o, e := buildOpTree(path, &idata, caps)
 	if e != nil {
@@ -9,9 +9,15 @@
 package tree
 
 import (
+	"context"
 	"fmt"
+	"sort"
 	"strings"
 
+	"github.com/danos/op/alias"
+	"github.com/danos/op/auth"
+	"github.com/danos/op/matching"
+	"github.com/danos/op/suggest"
 	"github.com/danos/op/tmpl"
 	"github.com/danos/utils/pathutil"
 )
@@ -25,6 +31,9 @@ const (
 	PErrAmbig
 )
 
+//SecretMask replaces secret path elements in messages
+const SecretMask = "****"
+
 //Path is a representation of an operational path
 type Path []string
 
@@ -41,7 +50,7 @@ func (p Path) StringByAttrs(attrs *pathutil.PathAttrs) string {
 
 	for i, e := range p {
 		if attrs.Attrs[i].Secret {
-			np = append(np, "****")
+			np = append(np, SecretMask)
 		} else {
 			np = append(np, e)
 		}
@@ -54,12 +63,53 @@ func (p Path) String() string {
 	return strings.Join(p, " ")
 }
 
+//CommandInvalid is the error generated for PErrInval. Suggestions holds the
+//candidates closest to the failing element, best first.
+type CommandInvalid struct {
+	Path        string
+	Fail        string
+	Suggestions []string
+}
+
+func (e *CommandInvalid) Error() string {
+	return fmt.Sprintf("Invalid command: %s [%s]", e.Path, e.Fail) +
+		suggest.Format(e.Suggestions)
+}
+
 //PathErrorf Takes an error type, a path, the element the path failed, and a list of matches
 //then it generates an appropriate error message coresponding to the current CLI style.
+//For PErrInval the matches are the authorised candidates at the failing position,
+//from which suggestions are made.
 func PathErrorf(etype PErr, p Path, eelem string, matches []*OpTree) error {
+	return pathErrorf(etype, p.String(), eelem, matches)
+}
+
+//PathErrorfByAttrs is PathErrorf with the elements of the path marked
+//secret in attrs masked, as by StringByAttrs
+func PathErrorfByAttrs(
+	etype PErr,
+	p Path,
+	attrs *pathutil.PathAttrs,
+	eelem string,
+	matches []*OpTree,
+) error {
+	return pathErrorf(etype, p.StringByAttrs(attrs), eelem, matches)
+}
+
+func pathErrorf(etype PErr, p string, eelem string, matches []*OpTree) error {
 	switch etype {
 	case PErrInval:
-		return fmt.Errorf("Invalid command: %s [%s]", p, eelem)
+		var names []string
+		for _, m := range matches {
+			if m.Name() != "node.tag" {
+				names = append(names, m.Name())
+			}
+		}
+		return &CommandInvalid{
+			Path:        p,
+			Fail:        eelem,
+			Suggestions: suggest.Rank(eelem, names),
+		}
 	case PErrIncomp:
 		return fmt.Errorf("Incomplete command: %s %s", p, eelem)
 	case PErrAmbig:
@@ -90,8 +140,23 @@ type ChildIterator struct {
 	t       *OpTree
 }
 
-//NewChildIterator creates a child iterator for the provided tree.
+//NewChildIterator creates a child iterator for the provided tree. Hidden
+//children are skipped.
 func NewChildIterator(t *OpTree) *ChildIterator {
+	i := NewChildIteratorAll(t)
+	keys := i.keys[:0]
+	for _, k := range i.keys {
+		if c, _ := t.Child(k); !c.Value().Hidden() {
+			keys = append(keys, k)
+		}
+	}
+	i.keys = keys
+	return i
+}
+
+//NewChildIteratorAll creates a child iterator for the provided tree which
+//includes hidden children, as when generating documentation.
+func NewChildIteratorAll(t *OpTree) *ChildIterator {
 	var keys []string
 	for k := range t.children {
 		keys = append(keys, k)
@@ -134,6 +199,24 @@ func (i *ChildIterator) Next() {
 	}
 }
 
+//childList returns the visible child nodes, including those that are included.
+func (t *OpTree) childList() []*OpTree {
+	var chs []*OpTree
+	for i := NewChildIterator(t); i.HasNext(); i.Next() {
+		chs = append(chs, i.Value())
+	}
+	return chs
+}
+
+//childListAll returns all child nodes, including those that are hidden.
+func (t *OpTree) childListAll() []*OpTree {
+	var chs []*OpTree
+	for i := NewChildIteratorAll(t); i.HasNext(); i.Next() {
+		chs = append(chs, i.Value())
+	}
+	return chs
+}
+
 //OpTree is a representation of the operational mode template tree.
 type OpTree struct {
 	name     string
@@ -164,7 +247,7 @@ func (t *OpTree) Print(depth int) {
 	if t.value != nil {
 		fmt.Printf("value %s\n", t.Value())
 	}
-	for it := NewChildIterator(t); it.HasNext(); it.Next() {
+	for it := NewChildIteratorAll(t); it.HasNext(); it.Next() {
 		c := it.Value()
 		c.Print(depth + 1)
 	}
@@ -290,3 +373,309 @@ func (t *OpTree) Descendant(p Path) (*OpTree, error) {
 
 	return ct, err
 }
+
+//Expand expands each element of a possibly abbreviated path to the name
+//of the node it matches, according to the matching policy. Elements which
+//match no node are taken as the value of a tag node, if there is one.
+//Hidden nodes are only matched by their full name. Under the Subsequence
+//policy an element may span several nodes, as split by the policy. Only
+//nodes a permits the user carried by ctx to expand are matched, and only
+//those it permits them to complete are suggested when an element matches
+//nothing. A nil a permits everything. Secret values are masked in the
+//errors returned.
+func (t *OpTree) Expand(
+	ctx context.Context,
+	p Path,
+	policy *matching.Policy,
+	a auth.Authoriser,
+) (Path, error) {
+	a = cache(a)
+	p = policy.Split(p, t.splitChildren(ctx, a))
+	ep := make(Path, 0, len(p))
+	n := t
+	for _, v := range p {
+		chs, err := permitted(ctx, a, auth.Expand, ep, n.childListAll())
+		if err != nil {
+			return nil, err
+		}
+		next, matches := n.step(v, policy, chs)
+		switch {
+		case next != nil:
+			if next.Name() == "node.tag" {
+				ep = append(ep, v)
+			} else {
+				ep = append(ep, next.Name())
+			}
+			n = next
+		case len(matches) == 0:
+			return nil, t.invalid(ctx, a, ep, n, v)
+		default:
+			return nil, PathErrorfByAttrs(PErrAmbig, ep, t.expandedPathAttrs(ep), v,
+				matches)
+		}
+	}
+	return ep, nil
+}
+
+//splitChildren returns the visible children of the nodes on expanded
+//paths which a permits the user carried by ctx to expand, for splitting
+//elements spanning several nodes.
+func (t *OpTree) splitChildren(ctx context.Context, a auth.Authoriser) matching.Children {
+	return func(ep []string) ([]string, bool) {
+		n, err := t.Descendant(ep)
+		if err != nil {
+			return nil, false
+		}
+		chs, err := permitted(ctx, a, auth.Expand, ep, n.childList())
+		if err != nil {
+			return nil, false
+		}
+		var names []string
+		value := false
+		for _, c := range chs {
+			if c.Name() == "node.tag" {
+				value = true
+			} else {
+				names = append(names, c.Name())
+			}
+		}
+		return names, value
+	}
+}
+
+//step matches v, an element of a possibly abbreviated path, against chs,
+//children of t, according to the matching policy. It returns the child
+//matched, which is the tag node if v matches no other, or the children v
+//is ambiguous between. Neither is returned if v matches nothing.
+func (t *OpTree) step(
+	v string,
+	policy *matching.Policy,
+	chs []*OpTree,
+) (*OpTree, []*OpTree) {
+	byName := make(map[string]*OpTree)
+	var names []string
+	var tag *OpTree
+	for _, c := range chs {
+		switch {
+		case c.Name() == "node.tag":
+			tag = c
+		case c.Value().Hidden():
+			if policy.Exact(c.Name(), v) {
+				return c, nil
+			}
+		default:
+			byName[c.Name()] = c
+			names = append(names, c.Name())
+		}
+	}
+	sort.Strings(names)
+
+	var matches []*OpTree
+	for _, name := range policy.Filter(v, names) {
+		if policy.Exact(name, v) {
+			return byName[name], nil
+		}
+		matches = append(matches, byName[name])
+	}
+
+	switch len(matches) {
+	case 0:
+		return tag, nil
+	case 1:
+		return matches[0], nil
+	}
+	return nil, matches
+}
+
+//ExpandAliases is Expand for a path which may start with one of aliases.
+//The alias is rewritten before the path is expanded, unless it shadows a
+//command of t or one of the commands of other sources carried by ctx.
+func (t *OpTree) ExpandAliases(
+	ctx context.Context,
+	p Path,
+	policy *matching.Policy,
+	aliases *alias.Table,
+	a auth.Authoriser,
+) (Path, error) {
+	var commands []string
+	for i := NewChildIteratorAll(t); i.HasNext(); i.Next() {
+		commands = append(commands, i.Value().Name())
+	}
+	rp, err := aliases.Rewrite(p, append(commands, alias.Commands(ctx)...))
+	if err != nil {
+		return nil, err
+	}
+	return t.Expand(ctx, rp, policy, a)
+}
+
+//ExpandWithDeprecation is Expand, also returning the deprecation of the
+//deepest deprecated node on the expanded path, if any.
+func (t *OpTree) ExpandWithDeprecation(
+	ctx context.Context,
+	p Path,
+	policy *matching.Policy,
+	a auth.Authoriser,
+) (Path, *tmpl.Deprecation, error) {
+	ep, err := t.Expand(ctx, p, policy, a)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ep, t.Deprecation(ep), nil
+}
+
+//Deprecation returns the deprecation of the deepest deprecated node on an
+//expanded path, or nil if there is none. A deprecated command's arguments
+//and sub-commands are deprecated along with it.
+func (t *OpTree) Deprecation(p Path) *tmpl.Deprecation {
+	var dep *tmpl.Deprecation
+	n := t
+	for i, v := range p {
+		c, err := n.ChildOrTag(v)
+		if err != nil {
+			break
+		}
+		if d := tmpl.NewDeprecation(p[:i+1], c.Value()); d != nil {
+			dep = d
+		}
+		n = c
+	}
+	return dep
+}
+
+//Resolve walks a given path returning the template to run along with the
+//value the user gave for each tag node in the path. Each value is bound
+//to the name of the node the tag belongs to. The path must be expanded,
+//naming each node in full; abbreviations are not matched. Only nodes a
+//permits the user carried by ctx to execute are walked, so a denied
+//command is reported as invalid. If an element names no such node, the
+//commands a permits the user to complete there are suggested; a nil a
+//permits everything.
+func (t *OpTree) Resolve(
+	ctx context.Context,
+	p Path,
+	a auth.Authoriser,
+) (*tmpl.Invocation, error) {
+	a = cache(a)
+	inv := &tmpl.Invocation{Path: p}
+	n := t
+	for i, v := range p {
+		chs, err := permitted(ctx, a, auth.Execute, p[:i], n.childListAll())
+		if err != nil {
+			return nil, err
+		}
+		var c, tag *OpTree
+		for _, ch := range chs {
+			switch ch.Name() {
+			case v:
+				c = ch
+			case "node.tag":
+				tag = ch
+			}
+		}
+		if c == nil && tag != nil {
+			ok, err := authorised(ctx, a, auth.Execute, p[:i+1])
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				c = tag
+				inv.Bindings = append(inv.Bindings, tmpl.Binding{
+					Name:   n.Name(),
+					Value:  v,
+					Type:   "txt",
+					Secret: c.Value().Secret(),
+					Index:  i,
+				})
+			}
+		}
+		if c == nil {
+			return nil, t.invalid(ctx, a, p[:i], n, v)
+		}
+		n = c
+	}
+	inv.Template = n.Value()
+	return inv, nil
+}
+
+//invalid returns the error for v, following the expanded path p, matching
+//no child of n, the node at p. The children of n which a permits the user
+//to complete are offered as suggestions.
+func (t *OpTree) invalid(
+	ctx context.Context,
+	a auth.Authoriser,
+	p Path,
+	n *OpTree,
+	v string,
+) error {
+	cands, err := permitted(ctx, a, auth.Complete, p, n.childList())
+	if err != nil {
+		return err
+	}
+	return PathErrorfByAttrs(PErrInval, p, t.expandedPathAttrs(p), v, cands)
+}
+
+//Completion returns the names and help text of the visible children of an
+//expanded path which a permits the user carried by ctx to complete. A nil
+//a permits everything. A tag node is named by its parent in angle
+//brackets, as a placeholder for the value it takes.
+func (t *OpTree) Completion(
+	ctx context.Context,
+	p Path,
+	a auth.Authoriser,
+) (map[string]string, error) {
+	n, err := t.Descendant(p)
+	if err != nil {
+		return nil, err
+	}
+	chs, err := permitted(ctx, a, auth.Complete, p, n.childList())
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]string)
+	for _, c := range chs {
+		name := c.Name()
+		if name == "node.tag" {
+			name = "<" + n.Name() + ">"
+		}
+		m[name] = strings.TrimSpace(c.Value().Help())
+	}
+	return m, nil
+}
+
+//PathAttrs generates the PathAttrs for a path, which may be abbreviated as
+//for Expand. Values of tag nodes whose template is marked secret are
+//themselves marked secret. Elements which cannot be matched are not.
+func (t *OpTree) PathAttrs(p Path) *pathutil.PathAttrs {
+	attrs := pathutil.NewPathAttrs()
+	n := t
+	for _, v := range p {
+		attr := pathutil.NewPathElementAttrs()
+		if n != nil {
+			n, _ = n.step(v, nil, n.childListAll())
+			attr.Secret = n != nil && n.Name() == "node.tag" &&
+				n.Value().Secret()
+		}
+		attrs.Attrs = append(attrs.Attrs, attr)
+	}
+	return &attrs
+}
+
+//expandedPathAttrs is PathAttrs for a path which has already been
+//expanded, so whose elements name nodes exactly.
+func (t *OpTree) expandedPathAttrs(p Path) *pathutil.PathAttrs {
+	attrs := pathutil.NewPathAttrs()
+	n := t
+	for _, v := range p {
+		attr := pathutil.NewPathElementAttrs()
+		if n != nil {
+			c, err := n.Child(v)
+			if err != nil {
+				c, _ = n.Child("node.tag")
+				attr.Secret = c != nil && c.Value().Secret()
+			}
+			n = c
+		}
+		attrs.Attrs = append(attrs.Attrs, attr)
+	}
+	return &attrs
+}
@@ -6,8 +6,14 @@
 package tree
 
 import (
+	"context"
+	"fmt"
 	"testing"
 
+	"github.com/danos/op/alias"
+	"github.com/danos/op/auth"
+	"github.com/danos/op/matching"
+	"github.com/danos/op/tmpl"
 	"github.com/danos/utils/pathutil"
 )
 
@@ -91,3 +97,440 @@ func TestStringByAttrsMultiElem(t *testing.T) {
 	a.Attrs[2].Secret = true
 	doStringByAttrsTest(t, p, &a, "show ip ****")
 }
+
+func buildTestTree() *OpTree {
+	root := NewOpTree("templates", nil)
+	show := NewOpTree("show", tmpl.NewOpTmpl("", "Show", "", ""))
+	root.AddChild(show)
+
+	intf := NewOpTree("interfaces", tmpl.NewOpTmpl("", "Interfaces", "", "show-intf"))
+	show.AddChild(intf)
+	eth := NewOpTree("ethernet", tmpl.NewOpTmpl("", "Ethernet", "", ""))
+	intf.AddChild(eth)
+	ethTag := NewOpTree("node.tag", tmpl.NewOpTmpl("", "Interface", "", "show-eth $4"))
+	eth.AddChild(ethTag)
+	ethTag.AddChild(NewOpTree("brief", tmpl.NewOpTmpl("", "Brief", "", "show-eth-brief $4")))
+
+	user := NewOpTree("user", tmpl.NewOpTmpl("", "User", "", ""))
+	show.AddChild(user)
+	userTag := NewOpTree("node.tag", tmpl.NewOpTmpl("", "User name", "", ""))
+	user.AddChild(userTag)
+	pass := NewOpTree("password", tmpl.NewOpTmpl("", "Password", "", ""))
+	userTag.AddChild(pass)
+	passTmpl := tmpl.NewOpTmpl("", "Password value", "", "check-pass $3 $5")
+	passTmpl.SetSecret(true)
+	pass.AddChild(NewOpTree("node.tag", passTmpl))
+	return root
+}
+
+func TestResolve(t *testing.T) {
+	root := buildTestTree()
+	ctx := context.Background()
+
+	inv, err := root.Resolve(ctx, Path{"show", "interfaces", "ethernet", "dp0s3", "brief"}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected resolve failure: %s", err)
+	}
+	if inv.Template.Run() != "show-eth-brief $4" {
+		t.Fatalf("Unexpected template: %s", inv.Template)
+	}
+	if len(inv.Bindings) != 1 {
+		t.Fatalf("Expected 1 binding, got %v", inv.Bindings)
+	}
+	b, ok := inv.Binding("ethernet")
+	if !ok || b.Value != "dp0s3" || b.Secret {
+		t.Fatalf("Unexpected binding for ethernet: %v", b)
+	}
+
+	inv, err = root.Resolve(ctx, Path{"show", "user", "fred", "password", "s3cret"}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected resolve failure: %s", err)
+	}
+	if len(inv.Bindings) != 2 {
+		t.Fatalf("Expected 2 bindings, got %v", inv.Bindings)
+	}
+	if b := inv.Bindings[0]; b.Name != "user" || b.Value != "fred" || b.Secret {
+		t.Fatalf("Unexpected first binding: %v", b)
+	}
+	if b := inv.Bindings[1]; b.Name != "password" || b.Value != "s3cret" || !b.Secret {
+		t.Fatalf("Unexpected second binding: %v", b)
+	}
+	if b := inv.Bindings[1]; b.Index != 4 {
+		t.Fatalf("Unexpected index for second binding: %d", b.Index)
+	}
+	if s := Path(inv.Path).StringByAttrs(inv.PathAttrs()); s != "show user fred password ****" {
+		t.Fatalf("Unexpected masked path: %s", s)
+	}
+}
+
+func TestResolveInvalid(t *testing.T) {
+	root := buildTestTree()
+	ctx := context.Background()
+
+	_, err := root.Resolve(ctx, Path{"show", "bogus"}, nil)
+	if err == nil {
+		t.Fatalf("Expected resolve failure")
+	}
+	if err.Error() != "Invalid command: show [bogus]" {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	_, err = root.Resolve(ctx, Path{"show", "user", "fred", "password", "s3cret", "bogus"}, nil)
+	if err == nil {
+		t.Fatalf("Expected resolve failure")
+	}
+	if err.Error() != "Invalid command: show user fred password **** [bogus]" {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	// Paths must be expanded
+	if _, err := root.Resolve(ctx, Path{"sh", "user", "fred"}, nil); err == nil {
+		t.Fatalf("Unexpected resolution of abbreviated path")
+	}
+}
+
+func TestPathAttrs(t *testing.T) {
+	root := buildTestTree()
+
+	tests := []struct {
+		path   Path
+		expect string
+	}{
+		{Path{"show", "user", "fred", "password", "s3cret"},
+			"show user fred password ****"},
+		{Path{"show", "user", "fred", "password", "s3cret", "extra"},
+			"show user fred password **** extra"},
+		{Path{"show", "interfaces", "ethernet", "dp0s3"},
+			"show interfaces ethernet dp0s3"},
+		{Path{"show", "bogus", "s3cret"},
+			"show bogus s3cret"},
+		{Path{"sh", "u", "fred", "pass", "s3cret"},
+			"sh u fred pass ****"},
+		{Path{"sh", "i", "e", "dp0s3"},
+			"sh i e dp0s3"},
+		{Path{}, ""},
+	}
+
+	for _, test := range tests {
+		attrs := root.PathAttrs(test.path)
+		if len(attrs.Attrs) != len(test.path) {
+			t.Errorf("Expected %d attributes for %s, got %d",
+				len(test.path), test.path, len(attrs.Attrs))
+			continue
+		}
+		if s := test.path.StringByAttrs(attrs); s != test.expect {
+			t.Errorf("Unexpected masked path:\n Expected - %s\n Got - %s\n",
+				test.expect, s)
+		}
+	}
+}
+
+func TestResolveSuggestions(t *testing.T) {
+	root := buildTestTree()
+	ctx := context.Background()
+
+	_, err := root.Resolve(ctx, Path{"show", "interfaces", "ethrenet"}, nil)
+	inval, ok := err.(*CommandInvalid)
+	if !ok {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(inval.Suggestions) != 1 || inval.Suggestions[0] != "ethernet" {
+		t.Fatalf("Unexpected suggestions: %v", inval.Suggestions)
+	}
+	expect := "Invalid command: show interfaces [ethrenet]\n\n  Did you mean:\n  ethernet"
+	if err.Error() != expect {
+		t.Fatalf("Unexpected error:\n%s", err)
+	}
+
+	_, err = root.Resolve(ctx, Path{"shwo"}, nil)
+	if inval, ok := err.(*CommandInvalid); !ok || len(inval.Suggestions) != 1 ||
+		inval.Suggestions[0] != "show" {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+type opAuthoriser struct {
+	ops  []auth.Operation
+	deny string
+}
+
+func (o *opAuthoriser) Authorise(ctx context.Context, req auth.Request) (auth.Decision, error) {
+	o.ops = append(o.ops, req.Op)
+	return auth.Decision{Permit: req.Path.String() != o.deny}, nil
+}
+
+func TestResolveAuthorised(t *testing.T) {
+	root := buildTestTree()
+	ctx := context.Background()
+
+	a := &opAuthoriser{deny: "show interfaces"}
+	if _, err := root.Resolve(ctx, Path{"show", "user", "fred"}, a); err != nil {
+		t.Fatalf("Unexpected resolve failure: %s", err)
+	}
+	for _, op := range a.ops {
+		if op != auth.Execute {
+			t.Fatalf("Unexpected operation authorised: %s", op)
+		}
+	}
+
+	// A denied command is not resolved, nor is a denied value
+	_, err := root.Resolve(ctx, Path{"show", "interfaces", "ethernet", "dp0s3", "brief"}, a)
+	if _, ok := err.(*CommandInvalid); !ok {
+		t.Fatalf("Unexpected error for denied command: %v", err)
+	}
+	a = &opAuthoriser{deny: "show user fred"}
+	_, err = root.Resolve(ctx, Path{"show", "user", "fred", "password", "s3cret"}, a)
+	if _, ok := err.(*CommandInvalid); !ok {
+		t.Fatalf("Unexpected error for denied value: %v", err)
+	}
+
+	// Denied commands are not suggested
+	root.AddChild(NewOpTree("shutdown", tmpl.NewOpTmpl("", "Shut down", "", "halt")))
+	denyShow := auth.Func(func(path []string) (bool, error) {
+		return len(path) == 0 || path[0] != "show", nil
+	})
+	_, err = root.Resolve(ctx, Path{"shwo"}, denyShow)
+	if ci, ok := err.(*CommandInvalid); !ok || len(ci.Suggestions) != 1 ||
+		ci.Suggestions[0] != "shutdown" {
+		t.Errorf("Unexpected suggestions: %v\n", err)
+	}
+}
+
+func TestExpand(t *testing.T) {
+	root := buildTestTree()
+	ctx := context.Background()
+	root.AddChild(NewOpTree("shutdown", tmpl.NewOpTmpl("", "Shut down", "", "halt")))
+
+	tests := []struct {
+		policy *matching.Policy
+		path   Path
+		expect string
+		err    string
+	}{
+		{nil, Path{"sho", "int", "eth", "dp0s3"},
+			"show interfaces ethernet dp0s3", ""},
+		{nil, Path{"sh"}, "",
+			"Ambiguous command:  [sh]\n\n  Possible completions:\n" +
+				"  show\t\tShow\n  shutdown\tShut down"},
+		{nil, Path{"SHO"}, "", "Invalid command:  [SHO]"},
+		{&matching.Policy{Mode: matching.CaseInsensitive}, Path{"SHO", "INT"},
+			"show interfaces", ""},
+		{&matching.Policy{Mode: matching.Subsequence}, Path{"show", "intf", "eth"},
+			"show interfaces ethernet", ""},
+		{&matching.Policy{Aliases: map[string]string{"display": "show"}},
+			Path{"display", "user", "fred", "password", "s3cret"},
+			"show user fred password s3cret", ""},
+		{nil, Path{"show", "user", "fred", "password", "s3cret", "x"}, "",
+			"Invalid command: show user fred password **** [x]"},
+		{&matching.Policy{Mode: matching.Subsequence}, Path{"shintf"},
+			"show interfaces", ""},
+		{&matching.Policy{Mode: matching.Subsequence}, Path{"shintfeth", "dp0s3"},
+			"show interfaces ethernet dp0s3", ""},
+		{nil, Path{"shintf"}, "", "Invalid command:  [shintf]"},
+		{&matching.Policy{Mode: matching.Subsequence}, Path{"shw"}, "",
+			"Ambiguous command:  [shw]\n\n  Possible completions:\n" +
+				"  show\t\tShow\n  shutdown\tShut down"},
+	}
+
+	for _, test := range tests {
+		ep, err := root.Expand(ctx, test.path, test.policy, nil)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("Expanding %s:\n Expected error - %q\n Got - %v\n",
+					test.path, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected failure expanding %s: %s\n", test.path, err)
+			continue
+		}
+		if ep.String() != test.expect {
+			t.Errorf("Expanding %s:\n Expected - %s\n Got - %s\n",
+				test.path, test.expect, ep)
+		}
+	}
+}
+
+func TestExpandAliases(t *testing.T) {
+	root := buildTestTree()
+	ctx := context.Background()
+	aliases := alias.NewTable()
+	err := aliases.Add(&alias.Alias{
+		Name:      "ethb",
+		Expansion: []string{"show", "int", "eth", "$1", "brief"},
+	}, []string{"show"})
+	if err != nil {
+		t.Fatalf("Unexpected failure adding alias: %s", err)
+	}
+
+	ep, err := root.ExpandAliases(ctx, Path{"ethb", "dp0s3"}, nil, aliases, nil)
+	if err != nil {
+		t.Fatalf("Unexpected expand failure: %s", err)
+	}
+	if ep.String() != "show interfaces ethernet dp0s3 brief" {
+		t.Fatalf("Unexpected expansion: %s", ep)
+	}
+
+	ep, err = root.ExpandAliases(ctx, Path{"sho", "int"}, nil, aliases, nil)
+	if err != nil || ep.String() != "show interfaces" {
+		t.Fatalf("Unexpected expansion: %s, %v", ep, err)
+	}
+
+	if _, err = root.ExpandAliases(ctx, Path{"ethb"}, nil, aliases, nil); err == nil {
+		t.Fatalf("Expected failure for missing parameter")
+	}
+
+	// Commands of other sources are shadowed too
+	octx := alias.WithCommands(ctx, []string{"ethbridge"})
+	_, err = root.ExpandAliases(octx, Path{"ethb", "dp0s3"}, nil, aliases, nil)
+	if _, ok := err.(*alias.ShadowError); !ok {
+		t.Fatalf("Expected shadow error, got %v", err)
+	}
+}
+
+func TestExpandAuthorised(t *testing.T) {
+	root := buildTestTree()
+	root.AddChild(NewOpTree("shutdown", tmpl.NewOpTmpl("", "Shut down", "", "halt")))
+	ctx := context.Background()
+	denyShow := auth.Func(func(path []string) (bool, error) {
+		return len(path) == 0 || path[0] != "show", nil
+	})
+
+	// Denied commands are neither matched nor suggested
+	if ep, err := root.Expand(ctx, Path{"sh"}, nil, denyShow); err != nil ||
+		ep.String() != "shutdown" {
+		t.Errorf("Unexpected expansion: %v, %v\n", ep, err)
+	}
+	_, err := root.Expand(ctx, Path{"shwo"}, nil, denyShow)
+	if ci, ok := err.(*CommandInvalid); !ok || len(ci.Suggestions) != 1 ||
+		ci.Suggestions[0] != "shutdown" {
+		t.Errorf("Unexpected suggestions: %v\n", err)
+	}
+
+	// Without an authoriser the closer match is suggested first
+	_, err = root.Expand(ctx, Path{"shwo"}, nil, nil)
+	if ci, ok := err.(*CommandInvalid); !ok || len(ci.Suggestions) != 2 ||
+		ci.Suggestions[0] != "show" {
+		t.Errorf("Unexpected suggestions: %v\n", err)
+	}
+
+	failure := auth.Func(func(path []string) (bool, error) {
+		return false, fmt.Errorf("authoriser unavailable")
+	})
+	if _, err := root.Expand(ctx, Path{"sh"}, nil, failure); err == nil {
+		t.Errorf("Expected authoriser failure\n")
+	} else if _, ok := err.(*auth.Error); !ok {
+		t.Errorf("Unexpected error: %v\n", err)
+	}
+}
+
+func addRetiredCommands(root *OpTree) {
+	shell := tmpl.NewOpTmpl("", "Debug shell", "", "sh")
+	shell.SetHidden(true)
+	root.AddChild(NewOpTree("shell", shell))
+
+	show, _ := root.Child("show")
+	old := tmpl.NewOpTmpl("", "Old interfaces", "", "show-intf")
+	old.SetDeprecated("Replaced by show interfaces")
+	old.SetReplacement("show interfaces")
+	intfs := NewOpTree("intfs", old)
+	show.AddChild(intfs)
+	intfs.AddChild(NewOpTree("node.tag", tmpl.NewOpTmpl("", "Interface", "", "")))
+}
+
+func TestHidden(t *testing.T) {
+	root := buildTestTree()
+	ctx := context.Background()
+	addRetiredCommands(root)
+
+	count := func(i *ChildIterator) int {
+		n := 0
+		for ; i.HasNext(); i.Next() {
+			n++
+		}
+		return n
+	}
+	if n := count(NewChildIterator(root)); n != 1 {
+		t.Errorf("Expected hidden child to be skipped, got %d children\n", n)
+	}
+	if n := count(NewChildIteratorAll(root)); n != 2 {
+		t.Errorf("Expected hidden child to be included, got %d children\n", n)
+	}
+
+	// Hidden commands run when named in full, but are not abbreviated
+	// or suggested
+	if ep, err := root.Expand(ctx, Path{"shell"}, nil, nil); err != nil || ep.String() != "shell" {
+		t.Errorf("Unexpected expansion of hidden command: %v, %v\n", ep, err)
+	}
+	if ep, err := root.Expand(ctx, Path{"sh"}, nil, nil); err != nil || ep.String() != "show" {
+		t.Errorf("Unexpected expansion: %v, %v\n", ep, err)
+	}
+	_, err := root.Resolve(ctx, Path{"shel"}, nil)
+	if ci, ok := err.(*CommandInvalid); !ok || len(ci.Suggestions) != 1 ||
+		ci.Suggestions[0] != "show" {
+		t.Errorf("Unexpected suggestions: %v\n", err)
+	}
+}
+
+func TestDeprecation(t *testing.T) {
+	root := buildTestTree()
+	ctx := context.Background()
+	addRetiredCommands(root)
+
+	ep, dep, err := root.ExpandWithDeprecation(ctx, Path{"sh", "intfs", "dp0s3"}, nil, nil)
+	if err != nil {
+		t.Fatalf("Unexpected failure: %s\n", err)
+	}
+	if ep.String() != "show intfs dp0s3" || dep == nil {
+		t.Fatalf("Unexpected expansion: %v, %v\n", ep, dep)
+	}
+	expect := "Warning: 'show intfs' is deprecated: Replaced by show interfaces\n" +
+		"Use 'show interfaces' instead"
+	if dep.String() != expect {
+		t.Errorf("Expected deprecation:\n%s\nGot:\n%s\n", expect, dep)
+	}
+
+	if _, dep, _ := root.ExpandWithDeprecation(ctx, Path{"sh", "int"}, nil, nil); dep != nil {
+		t.Errorf("Unexpected deprecation: %s\n", dep)
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	root := buildTestTree()
+	ctx := context.Background()
+	addRetiredCommands(root)
+
+	m, err := root.Completion(ctx, Path{"show", "interfaces", "ethernet"}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected failure: %s\n", err)
+	}
+	if len(m) != 1 || m["<ethernet>"] != "Interface" {
+		t.Errorf("Unexpected completions: %v\n", m)
+	}
+
+	m, _ = root.Completion(ctx, Path{"show"}, nil)
+	if len(m) != 3 || m["interfaces"] != "Interfaces" || m["intfs"] == "" {
+		t.Errorf("Unexpected completions: %v\n", m)
+	}
+	if m, _ = root.Completion(ctx, nil, nil); len(m) != 1 {
+		t.Errorf("Expected hidden command to be omitted: %v\n", m)
+	}
+	if _, err := root.Completion(ctx, Path{"bogus"}, nil); err == nil {
+		t.Errorf("Expected failure for invalid path\n")
+	}
+
+	denyUser := auth.Func(func(path []string) (bool, error) {
+		return len(path) < 2 || path[1] != "user", nil
+	})
+	m, err = root.Completion(ctx, Path{"show"}, denyUser)
+	if _, ok := m["user"]; ok || err != nil || len(m) != 2 {
+		t.Errorf("Expected denied command to be omitted: %v, %v\n", m, err)
+	}
+	failure := auth.Func(func(path []string) (bool, error) {
+		return false, fmt.Errorf("authoriser unavailable")
+	})
+	if _, err := root.Completion(ctx, Path{"show"}, failure); err == nil {
+		t.Errorf("Expected authoriser failure\n")
+	}
+}
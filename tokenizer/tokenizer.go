@@ -0,0 +1,221 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+// Package tokenizer splits a raw command line into the path elements
+// taken by expansion and completion, as a shell would. Elements are
+// separated by unquoted white space. Single quotes preserve everything
+// up to the closing quote, double quotes everything but a backslash
+// escaping a double quote or backslash, and an unquoted backslash
+// escapes the character after it. An unquoted pipe is a token of its
+// own, separating a command from the filters applied to its output.
+package tokenizer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Token is an element of a command line
+type Token struct {
+	// Value is the element with quotes and escapes removed
+	Value string
+	// Start and End are the byte offsets of the token in the line, End
+	// being the offset after it
+	Start, End int
+	// Pipe is set for an unquoted pipe
+	Pipe bool
+}
+
+// Error is a command line which does not end cleanly
+type Error struct {
+	Offset int
+	Msg    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s at column %d", e.Msg, e.Offset+1)
+}
+
+// Line is a command line split into tokens, along with the position of
+// the cursor in it
+type Line struct {
+	Tokens []Token
+
+	// Cursor is the index of the token the cursor is in or at the end
+	// of, or if InToken is false, of the token which would be inserted
+	// at the cursor
+	Cursor   int
+	InToken  bool
+	line     string
+	position int
+}
+
+// Split splits line into tokens, locating the token containing the
+// cursor at byte offset cursor. A cursor outside the line is taken to
+// be at its end. If the line ends within quotes or after a backslash,
+// as a line which is still being typed may, the tokens are returned
+// along with an *Error; the last token holds what follows the quote.
+func Split(line string, cursor int) (*Line, error) {
+	if cursor < 0 || cursor > len(line) {
+		cursor = len(line)
+	}
+	tokens, err := scan(line)
+	l := &Line{Tokens: tokens, line: line, position: cursor}
+	l.Cursor = len(tokens)
+	for i, t := range tokens {
+		// A cursor next to a pipe is never in it, but starts a token
+		// before or after it
+		if cursor < t.Start || (t.Pipe && cursor == t.Start) {
+			l.Cursor = i
+			break
+		}
+		if cursor <= t.End && !t.Pipe {
+			l.Cursor, l.InToken = i, true
+			break
+		}
+	}
+	return l, err
+}
+
+func scan(line string) ([]Token, error) {
+	var tokens []Token
+	var val strings.Builder
+	start := -1
+	var quote rune
+	quoteAt := 0
+	escaped := false
+
+	end := func(i int) {
+		if start >= 0 {
+			tokens = append(tokens, Token{Value: val.String(), Start: start, End: i})
+			val.Reset()
+			start = -1
+		}
+	}
+
+	for i, r := range line {
+		switch {
+		case escaped:
+			val.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				val.WriteRune(r)
+			}
+		case quote == '"':
+			switch {
+			case r == '"':
+				quote = 0
+			case r == '\\' && i+1 < len(line) &&
+				(line[i+1] == '"' || line[i+1] == '\\'):
+				escaped = true
+			default:
+				val.WriteRune(r)
+			}
+		case r == ' ' || r == '\t' || r == '\n':
+			end(i)
+		case r == '|':
+			end(i)
+			tokens = append(tokens, Token{Value: "|", Start: i, End: i + 1, Pipe: true})
+		default:
+			if start < 0 {
+				start = i
+			}
+			switch r {
+			case '\\':
+				escaped = true
+			case '\'', '"':
+				quote, quoteAt = r, i
+			default:
+				val.WriteRune(r)
+			}
+		}
+	}
+	end(len(line))
+
+	switch {
+	case quote != 0:
+		return tokens, &Error{Offset: quoteAt, Msg: "Unterminated quote"}
+	case escaped:
+		return tokens, &Error{Offset: len(line) - 1, Msg: "Trailing backslash"}
+	}
+	return tokens, nil
+}
+
+// Values returns the values of tokens
+func Values(tokens []Token) []string {
+	vals := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		vals = append(vals, t.Value)
+	}
+	return vals
+}
+
+// Path returns the values of the tokens of the command, before any pipe
+func (l *Line) Path() []string {
+	return Values(l.Segments()[0])
+}
+
+// Segments returns the tokens between each unquoted pipe. There is
+// always at least one, possibly empty, segment.
+func (l *Line) Segments() [][]Token {
+	segs := [][]Token{nil}
+	for _, t := range l.Tokens {
+		if t.Pipe {
+			segs = append(segs, nil)
+			continue
+		}
+		segs[len(segs)-1] = append(segs[len(segs)-1], t)
+	}
+	return segs
+}
+
+// Before returns the values of the tokens before the cursor's token in
+// its segment of the line, the path to complete the cursor's token
+// from
+func (l *Line) Before() []string {
+	first := 0
+	for i := 0; i < l.Cursor; i++ {
+		if l.Tokens[i].Pipe {
+			first = i + 1
+		}
+	}
+	return Values(l.Tokens[first:l.Cursor])
+}
+
+// Prefix returns the value of the part of the cursor's token before
+// the cursor, "" if the cursor is not in a token
+func (l *Line) Prefix() string {
+	if !l.InToken {
+		return ""
+	}
+	t := l.Tokens[l.Cursor]
+	tokens, _ := scan(l.line[t.Start:l.position])
+	if len(tokens) == 0 {
+		return ""
+	}
+	return tokens[0].Value
+}
+
+// Quote returns s quoted, if need be, so that it is split as a single
+// token with value s
+func Quote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n'\"\\|") {
+		return s
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// Join returns path as a line, quoting elements as need be so that it
+// splits back into path
+func Join(path []string) string {
+	quoted := make([]string, 0, len(path))
+	for _, e := range path {
+		quoted = append(quoted, Quote(e))
+	}
+	return strings.Join(quoted, " ")
+}
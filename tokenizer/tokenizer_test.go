@@ -0,0 +1,141 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package tokenizer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		line   string
+		expect []string
+		err    string
+	}{
+		{"", []string{}, ""},
+		{"  show   interfaces\t", []string{"show", "interfaces"}, ""},
+		{`set description "link to core"`,
+			[]string{"set", "description", "link to core"}, ""},
+		{`show log | match 'a|b' | count`,
+			[]string{"show", "log", "|", "match", "a|b", "|", "count"}, ""},
+		{`show log|count`, []string{"show", "log", "|", "count"}, ""},
+		{`pass "it's \"here\""`, []string{"pass", `it's "here"`}, ""},
+		{`pass 'back\slash'`, []string{"pass", `back\slash`}, ""},
+		{`pass "back\slash\\"`, []string{"pass", `back\slash\`}, ""},
+		{`pass two\ words \|`, []string{"pass", "two words", "|"}, ""},
+		{`pass ab"c d"'e'f`, []string{"pass", "abc def"}, ""},
+		{`pass "" x`, []string{"pass", "", "x"}, ""},
+		{`pass "unfinished wo`, []string{"pass", "unfinished wo"},
+			"Unterminated quote at column 6"},
+		{`pass trailing\`, []string{"pass", "trailing"},
+			"Trailing backslash at column 14"},
+	}
+
+	for _, test := range tests {
+		l, err := Split(test.line, len(test.line))
+		if test.err == "" && err != nil {
+			t.Errorf("%s: unexpected failure: %s\n", test.line, err)
+		}
+		if test.err != "" && (err == nil || err.Error() != test.err) {
+			t.Errorf("%s:\n Expected error - %s\n Got - %v\n", test.line, test.err, err)
+		}
+		if vals := Values(l.Tokens); !reflect.DeepEqual(vals, test.expect) {
+			t.Errorf("%s:\n Expected - %q\n Got - %q\n", test.line, test.expect, vals)
+		}
+	}
+}
+
+func TestSplitOffsets(t *testing.T) {
+	line := `show  "a b" |count`
+	l, _ := Split(line, 0)
+	expect := []Token{
+		{Value: "show", Start: 0, End: 4},
+		{Value: "a b", Start: 6, End: 11},
+		{Value: "|", Start: 12, End: 13, Pipe: true},
+		{Value: "count", Start: 13, End: 18},
+	}
+	if !reflect.DeepEqual(l.Tokens, expect) {
+		t.Errorf("Expected - %v\n Got - %v\n", expect, l.Tokens)
+	}
+	if !reflect.DeepEqual(l.Path(), []string{"show", "a b"}) {
+		t.Errorf("Unexpected path: %q\n", l.Path())
+	}
+	if segs := l.Segments(); len(segs) != 2 || segs[1][0].Value != "count" {
+		t.Errorf("Unexpected segments: %v\n", segs)
+	}
+}
+
+func TestCursor(t *testing.T) {
+	line := `show interfaces "data plane" | mat x`
+	tests := []struct {
+		cursor  int
+		index   int
+		inToken bool
+		before  []string
+		prefix  string
+	}{
+		{0, 0, true, []string{}, ""},
+		{2, 0, true, []string{}, "sh"},
+		{4, 0, true, []string{}, "show"},
+		{5, 1, true, []string{"show"}, ""},
+		{10, 1, true, []string{"show"}, "inter"},
+		{15, 1, true, []string{"show"}, "interfaces"},
+		{22, 2, true, []string{"show", "interfaces"}, "data "},
+		{28, 2, true, []string{"show", "interfaces"}, "data plane"},
+		{29, 3, false, []string{"show", "interfaces", "data plane"}, ""},
+		{30, 4, false, []string{}, ""},
+		{32, 4, true, []string{}, "m"},
+		{-1, 5, true, []string{"mat"}, "x"},
+		{100, 5, true, []string{"mat"}, "x"},
+	}
+
+	for _, test := range tests {
+		l, err := Split(line, test.cursor)
+		if err != nil {
+			t.Fatalf("Unexpected failure: %s\n", err)
+		}
+		if l.Cursor != test.index || l.InToken != test.inToken {
+			t.Errorf("Cursor %d: expected token %d (%t), got %d (%t)\n",
+				test.cursor, test.index, test.inToken, l.Cursor, l.InToken)
+		}
+		if before := l.Before(); !reflect.DeepEqual(before, test.before) {
+			t.Errorf("Cursor %d: expected before %q, got %q\n",
+				test.cursor, test.before, before)
+		}
+		if prefix := l.Prefix(); prefix != test.prefix {
+			t.Errorf("Cursor %d: expected prefix %q, got %q\n",
+				test.cursor, test.prefix, prefix)
+		}
+	}
+
+	l, _ := Split("show ", 5)
+	if l.Cursor != 1 || l.InToken || l.Prefix() != "" {
+		t.Errorf("Expected new token at end, got %d (%t)\n", l.Cursor, l.InToken)
+	}
+}
+
+func TestJoin(t *testing.T) {
+	for _, path := range [][]string{
+		{"show", "interfaces"},
+		{"set", "description", "link to core"},
+		{"pass", `it's "here"`, `back\slash`, "a|b", ""},
+	} {
+		line := Join(path)
+		l, err := Split(line, len(line))
+		if err != nil {
+			t.Errorf("%q: unexpected failure: %s\n", line, err)
+			continue
+		}
+		if !reflect.DeepEqual(l.Path(), path) {
+			t.Errorf("%q: expected %q, got %q\n", line, path, l.Path())
+		}
+	}
+
+	if s := Join([]string{"set", "description", "link to core"}); s !=
+		`set description "link to core"` {
+		t.Errorf("Unexpected quoting: %s\n", s)
+	}
+}
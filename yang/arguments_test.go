@@ -0,0 +1,173 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package yang
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/danos/op/tmpl"
+	"github.com/danos/utils/pathutil"
+)
+
+const multiArgSchema = `opd:command two-args {
+			opd:on-enter "two-args $3 $4";
+
+			opd:argument first {
+				opd:allowed "echo first-allowed";
+				type uint32;
+			}
+			opd:argument second {
+				opd:allowed "echo second-allowed";
+				opd:help "Second help";
+				type enumeration {
+					enum red;
+					enum green;
+				}
+			}
+			opd:option verbose {
+				type string;
+			}
+		}
+		opd:command three-args {
+			opd:on-enter "three-args";
+
+			opd:argument first {
+				type string;
+			}
+			opd:argument second {
+				type string;
+			}
+			opd:argument third {
+				opd:on-enter "third-on-enter";
+				type uint8 {
+					range 1..10;
+				}
+			}
+		}`
+
+func getMultiArgYang(t *testing.T) *Yang {
+	t.Helper()
+
+	y, err := GetTestYang([]byte(fmt.Sprintf(schemaTemplate, multiArgSchema)))
+	if err != nil {
+		t.Fatalf("Unexpected compilation failure:\n  %s\n\n", err.Error())
+	}
+	return y
+}
+
+func checkExpandPath(t *testing.T, y *Yang, path string, expect []string) {
+	t.Helper()
+
+	expands, err := y.Expand(pathutil.Makepath(path), nil)
+	if err != nil {
+		t.Errorf("Unexpected expand failure for '%s':\n  %s\n\n", path, err.Error())
+		return
+	}
+	if len(expands) != len(expect) {
+		t.Errorf("Expansion doesn't match expected:\n Expected - %v\n Got - %v\n",
+			expect, expands)
+		return
+	}
+	for i := range expect {
+		if expands[i] != expect[i] {
+			t.Errorf("Expansion doesn't match expected:\n Expected - %v\n Got - %v\n",
+				expect, expands)
+			return
+		}
+	}
+}
+
+func TestExpandMultipleArguments(t *testing.T) {
+	y := getMultiArgYang(t)
+
+	checkExpandPath(t, y, "two/10/red",
+		[]string{"two-args", "10", "red"})
+	checkExpandPath(t, y, "two/10/red/verb/on",
+		[]string{"two-args", "10", "red", "verbose", "on"})
+	checkExpandPath(t, y, "thr/a/b/3",
+		[]string{"three-args", "a", "b", "3"})
+
+	schema_text := bytes.NewBufferString(fmt.Sprintf(schemaTemplate, multiArgSchema))
+	checkExpandInvalid(t, schema_text, "/three-args/a/b/3/extra",
+		[]string{"three-args", "a", "b", "3"}, "extra")
+}
+
+func TestCompletionMultipleArguments(t *testing.T) {
+	schema_text := bytes.NewBufferString(fmt.Sprintf(schemaTemplate, multiArgSchema))
+
+	checkCompletionSuccess(t, schema_text, "two-args/10",
+		map[string]string{
+			"<second>": "Second help",
+			"verbose":  "",
+		})
+}
+
+func checkChildren(t *testing.T, y *Yang, path string, expect []string) {
+	t.Helper()
+
+	chs, err := y.TmplGetChildren(pathutil.Makepath(path), nil)
+	if err != nil {
+		t.Errorf("Unexpected TmplGetChildren failure:\n  %s\n\n", err.Error())
+		return
+	}
+	if len(chs) != len(expect) {
+		t.Errorf("Children don't match expected:\n Expected - %v\n Got - %v\n",
+			expect, chs)
+		return
+	}
+	for _, v := range expect {
+		if !isInSlice(chs, v) {
+			t.Errorf("Expected child not found: %s\n", v)
+		}
+	}
+}
+
+func checkAllowed(t *testing.T, y *Yang, path string, expect string) {
+	t.Helper()
+
+	allowed, err := y.TmplGetAllowed(pathutil.Makepath(path))
+	if err != nil {
+		t.Errorf("Unexpected TmplGetAllowed failure:\n  %s\n\n", err.Error())
+		return
+	}
+	if allowed != expect {
+		t.Errorf("Allowed doesn't match expected for '%s':\n Expected - %s\n Got - %s\n",
+			path, expect, allowed)
+	}
+}
+
+func TestChildrenMultipleArguments(t *testing.T) {
+	y := getMultiArgYang(t)
+
+	checkChildren(t, y, "two-args", []string{"verbose"})
+	checkChildren(t, y, "three-args", []string{})
+}
+
+func TestAllowedMultipleArguments(t *testing.T) {
+	y := getMultiArgYang(t)
+
+	checkAllowed(t, y, "two-args", "echo first-allowed")
+	checkAllowed(t, y, "two-args/10", "echo second-allowed")
+	checkAllowed(t, y, "two-args/10/red", "")
+}
+
+func TestGetMultipleArguments(t *testing.T) {
+	schema_text := bytes.NewBufferString(fmt.Sprintf(schemaTemplate, multiArgSchema))
+
+	checkGetSuccess(t, schema_text, "three-args/a/b/3",
+		tmpl.NewOpTmpl("", "", "", "third-on-enter"))
+}
+
+func TestValidateMultipleArguments(t *testing.T) {
+	schema_text := bytes.NewBufferString(fmt.Sprintf(schemaTemplate, multiArgSchema))
+
+	checkValidate(t, schema_text, "/two-args/10/red", true)
+	checkValidate(t, schema_text, "/two-args/10/blue", false)
+	checkValidate(t, schema_text, "/two-args/ten/red", false)
+	checkValidate(t, schema_text, "/three-args/a/b/3", true)
+	checkValidate(t, schema_text, "/three-args/a/b/30", false)
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package yang
+
+import (
+	"context"
+
+	"github.com/danos/op/auth"
+)
+
+// adapt converts a path authorisation function to an auth.Authoriser,
+// so that existing callers keep working with the context-aware API.
+// The legacy entry points cannot report a failure of the authoriser,
+// so it is treated as a denial.
+func (a Authoriser) adapt() auth.Authoriser {
+	if a == nil {
+		return nil
+	}
+	return auth.Func(func(path []string) (bool, error) {
+		permit, err := a(path)
+		if err != nil {
+			return false, nil
+		}
+		return permit, nil
+	})
+}
+
+// authoriser holds the state for authorising the paths visited by a
+// single request. Decisions are cached for the lifetime of the request.
+// The first failure of the authoriser is recorded so it can be reported
+// rather than treated as a denial.
+type authoriser struct {
+	ctx  context.Context
+	user auth.User
+	op   auth.Operation
+	auth *auth.Cache
+	err  error
+}
+
+func newAuthoriser(
+	ctx context.Context,
+	op auth.Operation,
+	a auth.Authoriser,
+) *authoriser {
+	user, _ := auth.UserFromContext(ctx)
+	az := &authoriser{ctx: ctx, user: user, op: op}
+	if a != nil {
+		az.auth = auth.NewCache(a)
+	}
+	return az
+}
+
+// permitChildren decides on each of children of path together,
+// returning the set of those which may be used. The authoriser is
+// given its own copy of path, which the caller is free to reuse.
+func (a *authoriser) permitChildren(path []string, children []string) map[string]bool {
+	permits := make(map[string]bool, len(children))
+	if a.auth == nil {
+		for _, c := range children {
+			permits[c] = true
+		}
+		return permits
+	}
+	if len(children) == 0 {
+		return permits
+	}
+	apath := auth.NewPath(path...)
+	ds, err := a.auth.AuthoriseChildren(a.ctx,
+		auth.Request{User: a.user, Op: a.op, Path: apath}, children)
+	if err != nil {
+		if a.err == nil {
+			a.err = &auth.Error{Path: apath, Err: err}
+		}
+		return permits
+	}
+	for c, d := range ds {
+		permits[c] = d.Permit
+	}
+	return permits
+}
+
+// withOp returns an authoriser for a different operation within the
+// same request
+func (a *authoriser) withOp(op auth.Operation) *authoriser {
+	return &authoriser{ctx: a.ctx, user: a.user, op: op, auth: a.auth}
+}
@@ -10,9 +10,12 @@ package yang
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"testing"
 
+	"github.com/danos/op/auth"
 	"github.com/danos/utils/pathutil"
 )
 
@@ -145,16 +148,251 @@ func expandAction(path string, expects []string, auth Authoriser) opdAction {
 	}
 }
 
+// expandIncompleteAction succeeds only if path expands to expects, an
+// incomplete command. Where the path is denied it fails, so denials
+// are checked by passing the expansion that would otherwise result.
+func expandIncompleteAction(path string, expects []string, auth Authoriser) opdAction {
+	return func(y *Yang) error {
+		expands, err := y.Expand(pathutil.Makepath(path), auth)
+		if err == nil {
+			return fmt.Errorf("Did not see expected incomplete error for '%s'", path)
+		}
+		if _, ok := err.(*CommandIncomplete); !ok {
+			return err
+		}
+		if len(expects) != len(expands) {
+			return fmt.Errorf("Expands don't match expected:\n Expected - %v\n Got - %v\n", expects, expands)
+		}
+		for _, v := range expects {
+			if !isInSlice(expands, v) {
+				return fmt.Errorf("Expected expansion not found: %s\n", v)
+			}
+		}
+		return nil
+	}
+}
+
 func TestAuthoriseExpand(t *testing.T) {
-	checkAuthoriseAllow(t, expandAction("t", []string{"test-command"}, authoriseAllAllowed))
-	checkAuthoriseAllow(t, expandAction("a", []string{"another-command"}, authoriseAllAllowed))
-	checkAuthoriseDeny(t, expandAction("t", []string{}, authoriseNoneAllowed))
-	checkAuthoriseDeny(t, expandAction("a", []string{}, authoriseNoneAllowed))
-	checkAuthoriseDeny(t, expandAction("t", []string{}, authoriseDenyTestCommand))
-	checkAuthoriseAllow(t, expandAction("a", []string{"another-command"}, authoriseDenyTestCommand))
-	checkAuthoriseAllow(t, expandAction("t", []string{"test-command"}, authoriseAllowOnlyTestCommand))
-	checkAuthoriseDeny(t, expandAction("a", []string{}, authoriseAllowOnlyTestCommand))
-	checkAuthoriseDeny(t, expandAction("test-command/t", []string{}, authoriseDenyTestCommand))
-	checkAuthoriseAllow(t, expandAction("test-command/t", []string{"test-command", "test-option"}, authoriseAllowOnlyTestCommand))
-	checkAuthoriseDeny(t, expandAction("test-command", []string{}, authoriseDenyTestCommand))
+	// Neither command can be run, so each expands to an incomplete
+	// command when authorised
+	tc := []string{"test-command"}
+	ac := []string{"another-command"}
+	tco := []string{"test-command", "test-option"}
+
+	checkAuthoriseAllow(t, expandIncompleteAction("t", tc, authoriseAllAllowed))
+	checkAuthoriseAllow(t, expandIncompleteAction("a", ac, authoriseAllAllowed))
+	checkAuthoriseDeny(t, expandIncompleteAction("t", tc, authoriseNoneAllowed))
+	checkAuthoriseDeny(t, expandIncompleteAction("a", ac, authoriseNoneAllowed))
+	checkAuthoriseDeny(t, expandIncompleteAction("t", tc, authoriseDenyTestCommand))
+	checkAuthoriseAllow(t, expandIncompleteAction("a", ac, authoriseDenyTestCommand))
+	checkAuthoriseAllow(t, expandIncompleteAction("t", tc, authoriseAllowOnlyTestCommand))
+	checkAuthoriseDeny(t, expandIncompleteAction("a", ac, authoriseAllowOnlyTestCommand))
+	checkAuthoriseDeny(t, expandIncompleteAction("test-command/t", tco, authoriseDenyTestCommand))
+	checkAuthoriseAllow(t, expandIncompleteAction("test-command/t", tco, authoriseAllowOnlyTestCommand))
+	checkAuthoriseDeny(t, expandIncompleteAction("test-command", tc, authoriseDenyTestCommand))
+
+	// A value completes the command
+	checkAuthoriseAllow(t, expandAction("test-command/t/foo",
+		[]string{"test-command", "test-option", "foo"}, authoriseAllowOnlyTestCommand))
+	checkAuthoriseDeny(t, expandAction("test-command/t/foo",
+		[]string{"test-command", "test-option", "foo"}, authoriseDenyTestCommand))
+}
+
+type recordingAuthoriser struct {
+	reqs []auth.Request
+	deny string
+	err  error
+}
+
+func (r *recordingAuthoriser) Authorise(
+	ctx context.Context,
+	req auth.Request,
+) (auth.Decision, error) {
+	r.reqs = append(r.reqs, req)
+	if r.err != nil {
+		return auth.Decision{}, r.err
+	}
+	if req.Path.Elem(0) == r.deny {
+		return auth.Decision{Reason: "not for you"}, nil
+	}
+	return auth.Decision{Permit: true}, nil
+}
+
+func TestAuthoriseContextUser(t *testing.T) {
+	y := getYang(t, bytes.NewBufferString(fmt.Sprintf(schemaTemplate, schema_text)))
+
+	user := auth.User{Name: "fred", Groups: []string{"operator"}}
+	ctx := auth.NewContext(context.Background(), user)
+	ra := &recordingAuthoriser{deny: "another-command"}
+
+	comps, err := y.CompletionContext(ctx, []string{}, ra)
+	if err != nil {
+		t.Fatalf("Unexpected completion failure: %s", err)
+	}
+	if _, ok := comps["test-command"]; !ok || len(comps) != 1 {
+		t.Fatalf("Unexpected completions: %v", comps)
+	}
+	for _, req := range ra.reqs {
+		if req.User.Name != "fred" || req.Op != auth.Complete {
+			t.Fatalf("Unexpected authorisation request: %v", req)
+		}
+	}
+
+	ra.reqs = nil
+	if _, err := y.ExpandContext(ctx, []string{"t"}, ra); err != nil {
+		if _, ok := err.(*CommandIncomplete); !ok {
+			t.Fatalf("Unexpected expand failure: %s", err)
+		}
+	}
+	if len(ra.reqs) == 0 || ra.reqs[0].Op != auth.Expand {
+		t.Fatalf("Unexpected authorisation requests: %v", ra.reqs)
+	}
+}
+
+func TestAuthoriseContextFailure(t *testing.T) {
+	y := getYang(t, bytes.NewBufferString(fmt.Sprintf(schemaTemplate, schema_text)))
+
+	failure := errors.New("authoriser unavailable")
+	ra := &recordingAuthoriser{err: failure}
+
+	_, err := y.ExpandContext(context.Background(), []string{"t"}, ra)
+	if !errors.Is(err, failure) {
+		t.Fatalf("Expected authoriser failure from expand, got %v", err)
+	}
+	_, err = y.CompletionContext(context.Background(), []string{}, ra)
+	if !errors.Is(err, failure) {
+		t.Fatalf("Expected authoriser failure from completion, got %v", err)
+	}
+
+	// The legacy API cannot report failures, so treats them as denials
+	authFail := func(path []string) (bool, error) { return true, failure }
+	checkAuthoriseDeny(t, expandIncompleteAction("t", []string{"test-command"}, authFail))
+	checkAuthoriseAllow(t, completionAction("", []string{}, authFail))
+}
+
+type batchRecordingAuthoriser struct {
+	recordingAuthoriser
+	batches [][]string
+}
+
+func (b *batchRecordingAuthoriser) AuthoriseChildren(
+	ctx context.Context,
+	req auth.Request,
+	children []string,
+) (map[string]auth.Decision, error) {
+	b.batches = append(b.batches, children)
+	ds := make(map[string]auth.Decision)
+	for _, c := range children {
+		ds[c] = auth.Decision{Permit: req.Path.Len() > 0 || c != b.deny}
+	}
+	return ds, nil
+}
+
+func TestAuthoriseBatch(t *testing.T) {
+	y := getYang(t, bytes.NewBufferString(fmt.Sprintf(schemaTemplate, schema_text)))
+
+	ba := &batchRecordingAuthoriser{
+		recordingAuthoriser: recordingAuthoriser{deny: "another-command"}}
+
+	comps, err := y.CompletionContext(context.Background(), []string{}, ba)
+	if err != nil {
+		t.Fatalf("Unexpected completion failure: %s", err)
+	}
+	if _, ok := comps["test-command"]; !ok || len(comps) != 1 {
+		t.Fatalf("Unexpected completions: %v", comps)
+	}
+	if len(ba.batches) != 1 || len(ba.reqs) != 0 {
+		t.Fatalf("Expected a single batch, got %v and %v", ba.batches, ba.reqs)
+	}
+
+	ba.batches = nil
+	_, _, err = y.ExpandMatchesContext(context.Background(),
+		[]string{"test-command", "t"}, ba)
+	if err != nil {
+		t.Fatalf("Unexpected expand failure: %s", err)
+	}
+	if len(ba.batches) != 2 || len(ba.reqs) != 0 {
+		t.Fatalf("Expected one batch per element, got %v and %v", ba.batches, ba.reqs)
+	}
+}
+
+// pathRecorder keeps every path it is asked about, along with a
+// snapshot taken at the time of asking, so later mutation of a kept
+// path can be detected.
+type pathRecorder struct {
+	kept      [][]string
+	snapshots []string
+}
+
+func (p *pathRecorder) authorise(path []string) (bool, error) {
+	p.kept = append(p.kept, path)
+	p.snapshots = append(p.snapshots, fmt.Sprint(path))
+	return true, nil
+}
+
+func (p *pathRecorder) check(t *testing.T) {
+	t.Helper()
+
+	if len(p.kept) == 0 {
+		t.Fatalf("Authoriser was not asked about any paths")
+	}
+	for i, path := range p.kept {
+		if got := fmt.Sprint(path); got != p.snapshots[i] {
+			t.Errorf("Authorised path changed after the call:\n  Asked - %s\n  Now - %s\n",
+				p.snapshots[i], got)
+		}
+	}
+}
+
+type keepingAuthoriser struct {
+	paths     []auth.Path
+	snapshots []string
+}
+
+func (k *keepingAuthoriser) Authorise(
+	ctx context.Context,
+	req auth.Request,
+) (auth.Decision, error) {
+	k.paths = append(k.paths, req.Path)
+	k.snapshots = append(k.snapshots, req.Path.String())
+	return auth.Decision{Permit: true}, nil
+}
+
+func TestAuthorisePathsNotAliased(t *testing.T) {
+	y := getYang(t, bytes.NewBufferString(fmt.Sprintf(schemaTemplate, schema_text)))
+
+	// Give the caller's path spare capacity so that appending to it
+	// would share a backing array
+	path := make([]string, 0, 16)
+	path = append(path, "test", "test-o", "foo")
+
+	pr := &pathRecorder{}
+	if _, err := y.Expand(path, pr.authorise); err != nil {
+		if _, ok := err.(*CommandIncomplete); !ok {
+			t.Fatalf("Unexpected expand failure: %s", err)
+		}
+	}
+	pr.check(t)
+
+	pr = &pathRecorder{}
+	if _, err := y.Completion(path[:1], pr.authorise); err != nil {
+		t.Fatalf("Unexpected completion failure: %s", err)
+	}
+	pr.check(t)
+
+	ka := &keepingAuthoriser{}
+	if _, err := y.ExpandContext(context.Background(), path, ka); err != nil {
+		if _, ok := err.(*CommandIncomplete); !ok {
+			t.Fatalf("Unexpected expand failure: %s", err)
+		}
+	}
+	if len(ka.paths) == 0 {
+		t.Fatalf("Authoriser was not asked about any paths")
+	}
+	for i, p := range ka.paths {
+		if p.String() != ka.snapshots[i] {
+			t.Errorf("Authorised path changed after the call:\n  Asked - %s\n  Now - %s\n",
+				ka.snapshots[i], p)
+		}
+	}
 }
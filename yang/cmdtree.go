@@ -0,0 +1,192 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package yang
+
+import (
+	"strings"
+
+	"github.com/danos/config/schema"
+	"github.com/danos/op/cmdtree"
+)
+
+// CommandTree returns the command tree of the opd YANG, including
+// hidden commands, which are marked as such. Features disabled when
+// the model set was compiled are already absent; the if-feature
+// statements of those enabled are recovered from the modules.
+//
+// The options of a command may be given in any order, but a tree can
+// only show one, so they are listed in the order of the schema, each
+// at most once.
+func (y *Yang) CommandTree() *cmdtree.Node {
+	root := &cmdtree.Node{Source: cmdtree.Yang}
+	if y.stOpd == nil {
+		return root
+	}
+	b := cmdTreeBuilder{features: y.features.get()}
+	root.Children = b.children(y.stOpd, nil)
+	root.Sort()
+	return root
+}
+
+// cmdTreeBuilder builds the command tree of the opd YANG
+type cmdTreeBuilder struct {
+	features nodeFeatures
+}
+
+// continuesParent returns true if what follows sn is matched against
+// the children of its parent, as for an argument or option with no
+// children of its own
+func continuesParent(sn schema.Node) bool {
+	if _, ok := sn.(schema.OpdOption); ok {
+		return sn.Parent() != nil && len(sn.OpdChildren()) == 0
+	}
+	return isLeafArgument(sn)
+}
+
+// children returns the keywords which may follow sn, and the argument
+// a value following it binds to. As when expanding, the options of the
+// enclosing nodes remain available. given are the options and
+// arguments given on the path to sn since the nearest command.
+func (b cmdTreeBuilder) children(sn schema.Node, given []schema.Node) []*cmdtree.Node {
+	parent := sn
+	switch {
+	case continuesParent(sn):
+		parent = sn.Parent()
+	case !isOptionOrArgument(sn):
+		given = nil
+	}
+	isGiven := func(c schema.Node) bool {
+		for _, g := range given {
+			if g == c {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Only options following the last given are offered
+	kids := parent.OpdChildren()
+	first := 0
+	for i, c := range kids {
+		if _, ok := c.(schema.OpdOption); ok && isGiven(c) {
+			first = i + 1
+		}
+	}
+	var chs []*cmdtree.Node
+	for i, c := range kids {
+		if isElemOf(parent.Arguments(), c.Name()) {
+			continue
+		}
+		if _, ok := c.(schema.OpdOption); ok && i < first {
+			continue
+		}
+		chs = append(chs, b.keyword(c, given))
+	}
+	for _, opt := range scopeOptions(parent) {
+		if !isGiven(opt) && !hasChild(chs, opt.Name()) {
+			chs = append(chs, b.keyword(opt, given))
+		}
+	}
+	for _, name := range parent.Arguments() {
+		if arg, ok := parent.Child(name).(schema.OpdArgument); ok &&
+			!isGiven(arg) {
+			chs = append(chs, b.argument(arg, arg.Name(), given))
+			break
+		}
+	}
+	return chs
+}
+
+func isOptionOrArgument(sn schema.Node) bool {
+	switch sn.(type) {
+	case schema.OpdOption, schema.OpdArgument:
+		return true
+	}
+	return false
+}
+
+func hasChild(chs []*cmdtree.Node, name string) bool {
+	for _, c := range chs {
+		if c.Name == name && !c.Arg {
+			return true
+		}
+	}
+	return false
+}
+
+// runnable is implemented by the opd:command, opd:option and
+// opd:argument nodes
+type runnable interface {
+	OnEnter() string
+	Privileged() bool
+}
+
+func (b cmdTreeBuilder) node(sn schema.Node, name string) *cmdtree.Node {
+	n := &cmdtree.Node{
+		Name:        name,
+		Help:        schema.GetHelp(sn),
+		Description: strings.TrimSpace(sn.Description()),
+		Features:    b.features[schemaPath(sn)],
+		Hidden:      isHidden(sn),
+		Source:      cmdtree.Yang,
+	}
+	if r, ok := sn.(runnable); ok && r.OnEnter() != "" {
+		n.Runnable = true
+		n.Privileged = r.Privileged()
+	}
+	n.Deprecated, n.Replacement = deprecation(sn)
+	return n
+}
+
+func (b cmdTreeBuilder) keyword(sn schema.Node, given []schema.Node) *cmdtree.Node {
+	n := b.node(sn, sn.Name())
+	if _, ok := sn.(schema.OpdOption); ok {
+		given = append(given[:len(given):len(given)], sn)
+		if _, ok := sn.Type().(schema.Empty); !ok {
+			// The option takes a value before what follows it, so is
+			// only run with one
+			n.Runnable, n.Privileged = false, false
+			n.Children = []*cmdtree.Node{b.value(sn, sn.Name(), given)}
+			return n
+		}
+	}
+	n.Children = b.children(sn, given)
+	return n
+}
+
+// argument returns the node for the value of the argument sn
+func (b cmdTreeBuilder) argument(sn schema.Node, name string, given []schema.Node) *cmdtree.Node {
+	return b.value(sn, name, append(given[:len(given):len(given)], sn))
+}
+
+// value returns the node for the value of the argument or option sn
+func (b cmdTreeBuilder) value(sn schema.Node, name string, given []schema.Node) *cmdtree.Node {
+	n := b.node(sn, name)
+	n.Arg = true
+	n.Allowed = sn.ConfigdExt().OpdAllowed
+	n.Values = enumValues(sn.Type())
+	if n.Values == nil {
+		n.Values = cmdtree.StaticValues(n.Allowed)
+	}
+	n.Children = b.children(sn, given)
+	return n
+}
+
+// enumValues returns the values of an enumeration, or of the
+// enumerations of a union
+func enumValues(ty schema.Type) []string {
+	var vals []string
+	switch t := ty.(type) {
+	case schema.Enumeration:
+		for _, e := range t.Enums() {
+			vals = append(vals, e.Val)
+		}
+	case schema.Union:
+		for _, ut := range t.Typs() {
+			vals = append(vals, enumValues(ut)...)
+		}
+	}
+	return vals
+}
@@ -0,0 +1,200 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package yang
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/danos/config/schema"
+	"github.com/danos/op/cmdtree"
+	"github.com/danos/yang/parse"
+)
+
+// findNode returns the node of tree at the path of space separated
+// names, or nil
+func findNode(tree *cmdtree.Node, path string) *cmdtree.Node {
+	n := tree
+	for _, name := range strings.Fields(path) {
+		var next *cmdtree.Node
+		for _, c := range n.Children {
+			if c.Name == name {
+				next = c
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		n = next
+	}
+	return n
+}
+
+const featuresSchema = `feature fancy;
+		feature plain;
+		opd:command show {
+			opd:command widgets {
+				if-feature fancy;
+				if-feature test:plain;
+				opd:on-enter "show-widgets";
+				opd:option colour {
+					if-feature plain;
+					type string;
+				}
+			}
+			opd:command gadgets {
+				opd:on-enter "show-gadgets";
+			}
+		}
+		opd:augment /test:show/test:gadgets {
+			opd:command all {
+				if-feature fancy;
+				opd:on-enter "show-gadgets --all";
+			}
+		}`
+
+func TestCommandTreeFeatures(t *testing.T) {
+	// The schema is compiled as though its features were enabled,
+	// leaving their if-feature statements in the parsed module only
+	unfeatured := strings.NewReplacer(
+		"if-feature fancy;", "", "if-feature plain;", "",
+		"if-feature test:plain;", "").Replace(featuresSchema)
+	y := getYang(t, bytes.NewBufferString(fmt.Sprintf(schemaTemplate, unfeatured)))
+
+	mod, err := schema.Parse("features", fmt.Sprintf(schemaTemplate, featuresSchema))
+	if err != nil {
+		t.Fatalf("Unexpected parse failure: %s\n", err)
+	}
+	y.features = parsedModules(map[string]*parse.Tree{
+		mod.Root.Argument().String(): mod,
+	})
+
+	tree := y.CommandTree()
+	for path, expect := range map[string][]string{
+		"show widgets": {"test-configd-compile:fancy",
+			"test-configd-compile:plain"},
+		"show widgets colour":        {"test-configd-compile:plain"},
+		"show widgets colour colour": {"test-configd-compile:plain"},
+		"show gadgets":               nil,
+		"show gadgets all":           {"test-configd-compile:fancy"},
+	} {
+		n := findNode(tree, path)
+		if n == nil {
+			t.Errorf("No node for %s\n", path)
+			continue
+		}
+		if !reflect.DeepEqual(n.Features, expect) {
+			t.Errorf("Unexpected features for %s:\n Expected - %v\n Got - %v\n",
+				path, expect, n.Features)
+		}
+	}
+}
+
+// keywordNames returns the names of the keywords of n
+func keywordNames(n *cmdtree.Node) []string {
+	var names []string
+	for _, c := range n.Keywords() {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+func TestCommandTreeOptions(t *testing.T) {
+	y := getYang(t, bytes.NewBufferString(fmt.Sprintf(schemaTemplate, optionsSchema)))
+	tree := y.CommandTree()
+
+	log := findNode(tree, "show log")
+	if log == nil || !log.Runnable {
+		t.Fatalf("Unexpected node for show log: %+v\n", log)
+	}
+	if names := keywordNames(log); !reflect.DeepEqual(names,
+		[]string{"file", "reverse", "tail"}) {
+		t.Errorf("Unexpected options of show log: %v\n", names)
+	}
+	tail := findNode(tree, "show log tail")
+	if tail == nil || tail.Runnable || tail.Argument() == nil ||
+		tail.Argument().Name != "tail" {
+		t.Fatalf("Unexpected node for tail option: %+v\n", tail)
+	}
+
+	// Each option may follow the value of another, in one order or
+	// the other, but none is given twice
+	given := map[string]string{
+		"tail":    "tail tail",
+		"file":    "file file",
+		"reverse": "reverse",
+	}
+	for a, pa := range given {
+		for b, pb := range given {
+			if a == b {
+				if findNode(tree, "show log "+pa+" "+pb) != nil {
+					t.Errorf("Option %s may be given twice\n", a)
+				}
+				continue
+			}
+			if findNode(tree, "show log "+pa+" "+pb) == nil &&
+				findNode(tree, "show log "+pb+" "+pa) == nil {
+				t.Errorf("Options %s and %s cannot be given together\n", a, b)
+			}
+		}
+	}
+}
+
+func TestCommandTreeArguments(t *testing.T) {
+	tree := getMultiArgYang(t).CommandTree()
+
+	first := findNode(tree, "two-args first")
+	if first == nil || !first.Arg {
+		t.Fatalf("Unexpected node for first argument: %+v\n", first)
+	}
+	if names := keywordNames(first); !reflect.DeepEqual(names,
+		[]string{"verbose"}) {
+		t.Errorf("Unexpected keywords after first argument: %v\n", names)
+	}
+	second := findNode(tree, "two-args first second")
+	if second == nil || !second.Arg ||
+		!reflect.DeepEqual(second.Values, []string{"red", "green"}) {
+		t.Fatalf("Unexpected node for second argument: %+v\n", second)
+	}
+
+	// The arguments follow the value of an option, wherever it is given
+	for _, path := range []string{
+		"two-args verbose verbose first second",
+		"two-args first verbose verbose second",
+	} {
+		if n := findNode(tree, path); n == nil || !n.Arg {
+			t.Errorf("No argument at %s\n", path)
+		}
+	}
+	if findNode(tree, "two-args first second first") != nil {
+		t.Errorf("Argument bound twice\n")
+	}
+
+	third := findNode(tree, "three-args first second third")
+	if third == nil || !third.Runnable || third.Children != nil {
+		t.Errorf("Unexpected node for third argument: %+v\n", third)
+	}
+}
+
+func TestCommandTreeRetired(t *testing.T) {
+	tree := getExtYang(t, retiredSchema).CommandTree()
+
+	old := findNode(tree, "old-ping")
+	if old == nil || !old.Hidden || old.Deprecated != "Use ping" ||
+		old.Replacement != "ping" {
+		t.Fatalf("Unexpected node for old-ping: %+v\n", old)
+	}
+	if host := findNode(tree, "old-ping host"); host == nil || host.Hidden {
+		t.Errorf("Unexpected node for old-ping argument: %+v\n", host)
+	}
+	ping := findNode(tree, "ping")
+	if ping == nil || ping.Hidden || ping.Deprecated != "" {
+		t.Errorf("Unexpected node for ping: %+v\n", ping)
+	}
+}
@@ -0,0 +1,30 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package yang
+
+import (
+	"github.com/danos/config/schema"
+	"github.com/danos/op/tmpl"
+)
+
+// setConfirm sets the confirmation prompt of template from the
+// op-ext:confirm statement used on sn or the nearest enclosing node up
+// to and including its opd:command, along with the default answer given
+// by op-ext:confirm-default on the same node.
+func setConfirm(template *tmpl.OpTmpl, sn schema.Node) {
+	for n := sn; n != nil; n = n.Parent() {
+		if prompt := opExtArg(n, "confirm"); prompt != "" {
+			template.SetConfirm(prompt)
+			def, err := tmpl.ParseAnswer(opExtArg(n, "confirm-default"))
+			if err == nil {
+				template.SetConfirmDefault(def)
+			}
+			return
+		}
+		if _, ok := n.(schema.OpdCommand); ok {
+			return
+		}
+	}
+}
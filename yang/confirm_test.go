@@ -0,0 +1,52 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package yang
+
+import (
+	"testing"
+)
+
+func TestGetConfirm(t *testing.T) {
+	y := getExtYang(t, `opd:command reboot {
+			op-ext:confirm "Reboot now?";
+			op-ext:confirm-default yes;
+			opd:on-enter "reboot";
+
+			opd:argument at {
+				opd:on-enter "reboot-at $2";
+				type string;
+			}
+		}
+		opd:command shutdown {
+			op-ext:confirm "Shut down now?";
+			opd:on-enter "shutdown";
+		}
+		opd:command uptime {
+			opd:on-enter "uptime";
+		}`)
+
+	tests := []struct {
+		path   []string
+		prompt string
+		def    bool
+	}{
+		{[]string{"reboot"}, "Reboot now?", true},
+		{[]string{"reboot", "10:00"}, "Reboot now?", true},
+		{[]string{"shutdown"}, "Shut down now?", false},
+		{[]string{"uptime"}, "", false},
+	}
+	for _, test := range tests {
+		template, err := y.TmplGet(test.path)
+		if err != nil {
+			t.Errorf("Unexpected TmplGet failure for %v: %s\n", test.path, err)
+			continue
+		}
+		if template.Confirm() != test.prompt ||
+			template.ConfirmDefault() != test.def {
+			t.Errorf("Unexpected confirmation for %v: %v\n",
+				test.path, template.Map())
+		}
+	}
+}
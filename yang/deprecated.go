@@ -0,0 +1,96 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package yang
+
+import (
+	"context"
+
+	"github.com/danos/config/schema"
+	"github.com/danos/op/auth"
+	"github.com/danos/op/tmpl"
+)
+
+// isHidden returns whether sn is marked op-ext:hidden
+func isHidden(sn schema.Node) bool {
+	_, ok := opExt(sn, "hidden")
+	return ok
+}
+
+// deprecation returns the argument of the op-ext:deprecated statement
+// used on sn, the warning, and of op-ext:replacement on the same node.
+// The warning is "" if sn is not deprecated.
+func deprecation(sn schema.Node) (string, string) {
+	warning := opExtArg(sn, "deprecated")
+	if warning == "" {
+		return "", ""
+	}
+	return warning, opExtArg(sn, "replacement")
+}
+
+// hidden returns whether sn is hidden from listings in az's context
+func (az *authoriser) hidden(sn schema.Node) bool {
+	return isHidden(sn) && !tmpl.ShowHidden(az.ctx)
+}
+
+// removeHidden removes the entries of m naming hidden children of sn
+func (az *authoriser) removeHidden(sn schema.Node, m map[string]string) {
+	for name := range m {
+		if c := sn.Child(name); c != nil && az.hidden(c) {
+			delete(m, name)
+		}
+	}
+}
+
+// setDeprecated sets the deprecation of template from the one declared
+// on sn or the nearest enclosing node, along with the replacement
+// declared with it. A deprecated command's arguments and sub-commands
+// are deprecated along with it.
+func setDeprecated(template *tmpl.OpTmpl, sn schema.Node) {
+	template.SetHidden(isHidden(sn))
+	for n := sn; n != nil; n = n.Parent() {
+		if warning, replacement := deprecation(n); warning != "" {
+			template.SetDeprecated(warning)
+			template.SetReplacement(replacement)
+			return
+		}
+	}
+}
+
+// ExpandWithDeprecation is ExpandContext, also returning the
+// deprecation of the deepest deprecated node on the expanded path, if
+// any. A deprecated command's arguments and sub-commands are
+// deprecated along with it.
+func (y *Yang) ExpandWithDeprecation(
+	ctx context.Context,
+	path []string,
+	a auth.Authoriser,
+) ([]string, *tmpl.Deprecation, error) {
+	epath, matches, err := y.expand(path, newAuthoriser(ctx, auth.Expand, a))
+	if epath == nil {
+		return nil, nil, err
+	}
+	return epath, matchDeprecation(epath, matches), err
+}
+
+func matchDeprecation(epath []string, matches [][]Match) *tmpl.Deprecation {
+	var dep *tmpl.Deprecation
+	for i := 0; i < len(epath) && i < len(matches); i++ {
+		for _, m := range matches[i] {
+			em, ok := m.(expandMatch)
+			if !ok || (len(matches[i]) > 1 && !em.isarg && em.Name() != epath[i]) {
+				continue
+			}
+			if warning, replacement := deprecation(em.node); warning != "" {
+				dep = &tmpl.Deprecation{
+					Path:        append([]string(nil), epath[:i+1]...),
+					Warning:     warning,
+					Replacement: replacement,
+				}
+			}
+			break
+		}
+	}
+	return dep
+}
@@ -0,0 +1,141 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package yang
+
+import (
+	"context"
+	"testing"
+
+	"github.com/danos/op/tmpl"
+)
+
+const retiredSchema = `opd:command ping {
+			opd:on-enter "ping";
+			opd:argument host {
+				opd:on-enter "ping $2";
+				type string;
+			}
+		}
+		opd:command old-ping {
+			op-ext:hidden;
+			op-ext:deprecated "Use ping";
+			op-ext:replacement "ping";
+			opd:on-enter "ping";
+			opd:argument host {
+				opd:on-enter "ping $2";
+				type string;
+			}
+		}`
+
+func TestGetRetired(t *testing.T) {
+	y := getExtYang(t, retiredSchema)
+
+	template, err := y.TmplGet([]string{"old-ping"})
+	if err != nil {
+		t.Fatalf("Unexpected TmplGet failure: %s\n", err)
+	}
+	if !template.Hidden() || template.Deprecated() != "Use ping" ||
+		template.Replacement() != "ping" {
+		t.Errorf("Unexpected template: %v\n", template.Map())
+	}
+
+	// The argument is deprecated along with its command, but not hidden
+	template, err = y.TmplGet([]string{"old-ping", "host1"})
+	if err != nil {
+		t.Fatalf("Unexpected TmplGet failure: %s\n", err)
+	}
+	if template.Hidden() || template.Deprecated() != "Use ping" {
+		t.Errorf("Unexpected argument template: %v\n", template.Map())
+	}
+
+	template, err = y.TmplGet([]string{"ping"})
+	if err != nil {
+		t.Fatalf("Unexpected TmplGet failure: %s\n", err)
+	}
+	if template.Hidden() || template.Deprecated() != "" {
+		t.Errorf("Unexpected template: %v\n", template.Map())
+	}
+}
+
+func TestGetRetiredSubCommand(t *testing.T) {
+	y := getExtYang(t, `opd:command old-show {
+			op-ext:deprecated "Use show";
+			op-ext:replacement "show";
+			opd:command version {
+				opd:on-enter "show-version";
+				opd:command detail {
+					opd:on-enter "show-version --detail";
+				}
+			}
+		}`)
+
+	// Sub-commands are deprecated along with the command enclosing
+	// them, however deep, as when expanding
+	for _, path := range [][]string{
+		{"old-show", "version"},
+		{"old-show", "version", "detail"},
+	} {
+		template, err := y.TmplGet(path)
+		if err != nil {
+			t.Fatalf("Unexpected TmplGet failure for %v: %s\n", path, err)
+		}
+		if template.Deprecated() != "Use show" ||
+			template.Replacement() != "show" {
+			t.Errorf("Unexpected template for %v: %v\n", path, template.Map())
+		}
+		_, dep, err := y.ExpandWithDeprecation(context.Background(), path, nil)
+		if err != nil || dep == nil || dep.Warning != template.Deprecated() {
+			t.Errorf("Unexpected deprecation of %v: %v, %v\n", path, dep, err)
+		}
+	}
+}
+
+func TestCompletionHidden(t *testing.T) {
+	y := getExtYang(t, retiredSchema)
+
+	comps, err := y.CompletionContext(context.Background(), []string{}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected completion failure: %s\n", err)
+	}
+	if _, ok := comps["old-ping"]; ok || len(comps) != 1 {
+		t.Errorf("Expected hidden command to be omitted: %v\n", comps)
+	}
+
+	comps, err = y.CompletionContext(tmpl.WithHidden(context.Background()),
+		[]string{}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected completion failure: %s\n", err)
+	}
+	if _, ok := comps["old-ping"]; !ok {
+		t.Errorf("Expected hidden command to be shown: %v\n", comps)
+	}
+}
+
+func TestExpandHidden(t *testing.T) {
+	y := getExtYang(t, retiredSchema)
+
+	// Hidden commands are only matched in full
+	if matches := y.ExpandMatches([]string{"old"}, nil); len(matches) != 1 ||
+		len(matches[0]) != 0 {
+		t.Errorf("Expected abbreviated hidden command not to match: %v\n",
+			matches)
+	}
+
+	epath, dep, err := y.ExpandWithDeprecation(context.Background(),
+		[]string{"old-ping", "host1"}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected expand failure: %s\n", err)
+	}
+	if len(epath) != 2 || dep == nil || dep.Warning != "Use ping" ||
+		dep.Replacement != "ping" || len(dep.Path) != 1 {
+		t.Errorf("Unexpected deprecation of %v: %v\n", epath, dep)
+	}
+
+	_, dep, err = y.ExpandWithDeprecation(context.Background(),
+		[]string{"p", "host1"}, nil)
+	if err != nil || dep != nil {
+		t.Errorf("Unexpected deprecation: %v, %v\n", dep, err)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package yang
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/danos/op/suggest"
+	"github.com/danos/utils/patherr"
+	"github.com/danos/utils/pathutil"
+)
+
+// CommandIncomplete is returned when a path expands successfully but
+// ends at a node that cannot be run without further elements, such as
+// an opd:command with no on-enter or an opd:option missing its value.
+// Matches holds the possible continuations and their help text. Path
+// elements marked secret in Attrs are masked in the error message.
+type CommandIncomplete struct {
+	Path    []string
+	Attrs   *pathutil.PathAttrs
+	Matches map[string]string
+}
+
+func (e *CommandIncomplete) Error() string {
+	errs := fmt.Sprintf("Incomplete command: %s",
+		strings.Join(maskPath(e.Path, e.Attrs), " "))
+	if len(e.Matches) == 0 {
+		return errs
+	}
+
+	names := make([]string, 0, len(e.Matches))
+	for name := range e.Matches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	errs = fmt.Sprintf("%s\n\n  Possible completions:\n", errs)
+	for i, name := range names {
+		help := strings.Trim(e.Matches[name], " \n\t")
+		if len(name) < 6 {
+			errs = fmt.Sprintf("%s  %s\t\t%s", errs, name, help)
+		} else {
+			errs = fmt.Sprintf("%s  %s\t%s", errs, name, help)
+		}
+		if i != len(names)-1 {
+			errs += "\n"
+		}
+	}
+	return errs
+}
+
+// CommandInvalid is returned by ExpandWithSuggestions when an element
+// of a path matches no command. It adds to the underlying
+// patherr.CommandInval the closest authorised candidates at the failing
+// position, best first. Other expansions return the
+// *patherr.CommandInval alone.
+type CommandInvalid struct {
+	*patherr.CommandInval
+	Suggestions []string
+}
+
+func (e *CommandInvalid) Error() string {
+	return e.CommandInval.Error() + suggest.Format(e.Suggestions)
+}
+
+func (e *CommandInvalid) Unwrap() error {
+	return e.CommandInval
+}
+
+// WithSuggestions adds suggestions drawn from candidates to err if it
+// is a *patherr.CommandInval, such as returned by ProcessMatches.
+// Other errors are returned unchanged. Candidates should hold only the
+// names the user is authorised to use at the failing position.
+func WithSuggestions(err error, candidates []string) error {
+	inval, ok := err.(*patherr.CommandInval)
+	if !ok {
+		return err
+	}
+	return &CommandInvalid{
+		CommandInval: inval,
+		Suggestions:  suggest.Rank(inval.Fail, candidates),
+	}
+}
@@ -10,9 +10,13 @@ package yang
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"testing"
 
+	"github.com/danos/op/alias"
+	"github.com/danos/op/auth"
+	"github.com/danos/op/matching"
 	"github.com/danos/utils/patherr"
 	"github.com/danos/utils/pathutil"
 )
@@ -42,6 +46,53 @@ func checkExpandSuccess(
 	}
 }
 
+func checkExpandIncomplete(
+	t *testing.T,
+	schema_text *bytes.Buffer,
+	path string,
+	expects []string,
+	continuations []string,
+	auth Authoriser,
+) {
+	t.Helper()
+	y, err := GetTestYang(schema_text.Bytes())
+
+	if err != nil {
+		t.Errorf("Unexpected compilation failure:\n  %s\n\n", err.Error())
+	}
+
+	expands, err := y.Expand(pathutil.Makepath(path), auth)
+
+	if err == nil {
+		t.Errorf("Did not see expected incomplete error for '%s'", path)
+		return
+	}
+
+	switch e := err.(type) {
+	case *CommandIncomplete:
+		if len(e.Path) != len(expects) {
+			t.Errorf("Unexpected incomplete path\n Got: %s\n Expected %s\n\n", e.Path, expects)
+		}
+		for _, v := range expects {
+			if !isInSlice(expands, v) {
+				t.Errorf("Expected expansion not found: %s\n", v)
+			}
+		}
+		if len(e.Matches) != len(continuations) {
+			t.Errorf("Unexpected continuations\n Got: %v\n Expected %v\n\n",
+				e.Matches, continuations)
+		}
+		for _, v := range continuations {
+			if _, ok := e.Matches[v]; !ok {
+				t.Errorf("Expected continuation not found: %s\n", v)
+			}
+		}
+
+	default:
+		t.Errorf("Unexpected expand error:\n %s\n\n", err.Error())
+	}
+}
+
 func checkExpandAmbiguous(
 	t *testing.T,
 	schema_text *bytes.Buffer,
@@ -138,13 +189,29 @@ func TestExpand(t *testing.T) {
 			}
 		}`))
 
-	checkExpandSuccess(t, schema_text, "t", []string{"test-command"})
-	checkExpandSuccess(t, schema_text, "test", []string{"test-command"})
-	checkExpandSuccess(t, schema_text, "a", []string{"another-command"})
-	checkExpandSuccess(t, schema_text, "another-co", []string{"another-command"})
-	checkExpandSuccess(t, schema_text, "/test-command/opt-o", []string{"opt-one"})
-	checkExpandSuccess(t, schema_text, "/test-command/opt-t", []string{"opt-two"})
-	checkExpandSuccess(t, schema_text, "/another-command/two/test", []string{"another-command", "two", "test-opt"})
+	// None of these commands can be run, so each expands to an
+	// incomplete command
+	checkExpandIncomplete(t, schema_text, "t",
+		[]string{"test-command"},
+		[]string{"opt-one", "opt-two"}, nil)
+	checkExpandIncomplete(t, schema_text, "test",
+		[]string{"test-command"},
+		[]string{"opt-one", "opt-two"}, nil)
+	checkExpandIncomplete(t, schema_text, "a",
+		[]string{"another-command"},
+		[]string{"one", "two", "three"}, nil)
+	checkExpandIncomplete(t, schema_text, "another-co",
+		[]string{"another-command"},
+		[]string{"one", "two", "three"}, nil)
+	checkExpandIncomplete(t, schema_text, "/test-command/opt-o",
+		[]string{"test-command", "opt-one"},
+		[]string{"<text>"}, nil)
+	checkExpandIncomplete(t, schema_text, "/test-command/opt-t",
+		[]string{"test-command", "opt-two"},
+		[]string{"<text>"}, nil)
+	checkExpandIncomplete(t, schema_text, "/another-command/two/test",
+		[]string{"another-command", "two", "test-opt"},
+		[]string{"<text>"}, nil)
 }
 
 func TestExpandOptionHelp(t *testing.T) {
@@ -283,9 +350,15 @@ func TestExpandPartialCommands(t *testing.T) {
 			}
 		}`))
 
-	checkExpandSuccess(t, schema_text, "t", []string{"test-command"})
-	checkExpandSuccess(t, schema_text, "/t/t", []string{"test-option"})
-	checkExpandSuccess(t, schema_text, "/t/test-o", []string{"test-option"})
+	checkExpandIncomplete(t, schema_text, "t",
+		[]string{"test-command"},
+		[]string{"<text>", "test-option", "another-command"}, nil)
+	checkExpandIncomplete(t, schema_text, "/t/t",
+		[]string{"test-command", "test-option"},
+		[]string{"<text>"}, nil)
+	checkExpandIncomplete(t, schema_text, "/t/test-o",
+		[]string{"test-command", "test-option"},
+		[]string{"<text>"}, nil)
 	checkExpandSuccess(t, schema_text, "/t/a", []string{"another-command"})
 
 	// Matches the opd:argument
@@ -325,3 +398,207 @@ func TestExpandInvalid(t *testing.T) {
 	// No Matching children after an opd:argument
 	checkExpandInvalid(t, schema_text, "/t/arg-val/foo", []string{"test-command", "arg-val"}, "foo")
 }
+
+func TestExpandIncomplete(t *testing.T) {
+	schema_text := bytes.NewBufferString(fmt.Sprintf(
+		schemaTemplate,
+		`opd:command test-command {
+			opd:help "Command help";
+
+			opd:option test-option {
+				opd:help "Option help";
+				opd:on-enter "test-option-on-enter";
+				type string;
+			}
+			opd:command another-command {
+				opd:help "Another command help text";
+				opd:on-enter "another-command-on-enter";
+			}
+			opd:command hidden-command {
+				opd:help "Hidden command help text";
+				opd:on-enter "hidden-command-on-enter";
+			}
+		}
+		opd:command runnable-command {
+			opd:on-enter "runnable-command-on-enter";
+
+			opd:option test-option {
+				type string;
+			}
+		}`))
+
+	authoriseNotHidden := func(path []string) (bool, error) {
+		return path[len(path)-1] != "hidden-command", nil
+	}
+
+	checkExpandIncomplete(t, schema_text, "t",
+		[]string{"test-command"},
+		[]string{"test-option", "another-command", "hidden-command"}, nil)
+	checkExpandIncomplete(t, schema_text, "t",
+		[]string{"test-command"},
+		[]string{"test-option", "another-command"}, authoriseNotHidden)
+	checkExpandIncomplete(t, schema_text, "t/test-o",
+		[]string{"test-command", "test-option"},
+		[]string{"<text>"}, nil)
+
+	checkExpandPath(t, getYang(t, schema_text), "t/test-o/foo",
+		[]string{"test-command", "test-option", "foo"})
+	checkExpandPath(t, getYang(t, schema_text), "t/a",
+		[]string{"test-command", "another-command"})
+	checkExpandPath(t, getYang(t, schema_text), "r",
+		[]string{"runnable-command"})
+}
+
+func getYang(t *testing.T, schema_text *bytes.Buffer) *Yang {
+	t.Helper()
+
+	y, err := GetTestYang(schema_text.Bytes())
+	if err != nil {
+		t.Fatalf("Unexpected compilation failure:\n  %s\n\n", err.Error())
+	}
+	return y
+}
+
+func TestExpandSuggestions(t *testing.T) {
+	y := getYang(t, bytes.NewBufferString(fmt.Sprintf(schemaTemplate, schema_text)))
+
+	// Suggestions are only made when asked for
+	_, err := y.Expand(pathutil.Makepath("tset-command"), nil)
+	if _, ok := err.(*patherr.CommandInval); !ok {
+		t.Fatalf("Unexpected error: %v\n", err)
+	}
+
+	_, err = y.ExpandWithSuggestions(context.Background(),
+		pathutil.Makepath("tset-command"), nil)
+	inval, ok := err.(*CommandInvalid)
+	if !ok {
+		t.Fatalf("Unexpected error: %v\n", err)
+	}
+	if len(inval.Suggestions) != 1 || inval.Suggestions[0] != "test-command" {
+		t.Errorf("Unexpected suggestions: %v\n", inval.Suggestions)
+	}
+
+	// Only authorised candidates are suggested
+	_, err = y.ExpandWithSuggestions(context.Background(),
+		pathutil.Makepath("tset-command"), auth.Func(authoriseDenyTestCommand))
+	inval, ok = err.(*CommandInvalid)
+	if !ok {
+		t.Fatalf("Unexpected error: %v\n", err)
+	}
+	if len(inval.Suggestions) != 0 {
+		t.Errorf("Unexpected suggestions: %v\n", inval.Suggestions)
+	}
+}
+
+func TestExpandMatchingPolicy(t *testing.T) {
+	y := getYang(t, bytes.NewBufferString(fmt.Sprintf(schemaTemplate, schema_text)))
+
+	expand := func(policy *matching.Policy, path string) ([]string, error) {
+		ctx := matching.NewContext(context.Background(), policy)
+		epath, err := y.ExpandContext(ctx, pathutil.Makepath(path), nil)
+		if _, ok := err.(*CommandIncomplete); ok {
+			err = nil
+		}
+		return epath, err
+	}
+
+	if _, err := expand(nil, "TEST"); err == nil {
+		t.Errorf("Expected case-sensitive match to fail by default\n")
+	}
+
+	tests := []struct {
+		policy *matching.Policy
+		path   string
+		expect []string
+	}{
+		{&matching.Policy{Mode: matching.CaseInsensitive}, "TEST/Test-O/foo",
+			[]string{"test-command", "test-option", "foo"}},
+		{&matching.Policy{Mode: matching.Subsequence}, "tstc/topt/foo",
+			[]string{"test-command", "test-option", "foo"}},
+		// An element may span several commands and options
+		{&matching.Policy{Mode: matching.Subsequence}, "anotopt/foo",
+			[]string{"another-command", "test-option", "foo"}},
+		{&matching.Policy{Aliases: map[string]string{"tc": "test-command"}}, "tc",
+			[]string{"test-command"}},
+	}
+	for _, test := range tests {
+		epath, err := expand(test.policy, test.path)
+		if err != nil {
+			t.Errorf("Unexpected failure expanding '%s': %s\n", test.path, err)
+			continue
+		}
+		if fmt.Sprint(epath) != fmt.Sprint(test.expect) {
+			t.Errorf("Expanding '%s':\n Expected - %v\n Got - %v\n",
+				test.path, test.expect, epath)
+		}
+	}
+
+	// Ambiguity is still reported
+	_, err := expand(&matching.Policy{Mode: matching.Subsequence}, "cmd")
+	if _, ok := err.(*patherr.PathAmbig); !ok {
+		t.Errorf("Expected ambiguous path, got %v\n", err)
+	}
+}
+
+func getAliases(t *testing.T, y *Yang) *alias.Table {
+	t.Helper()
+
+	aliases := alias.NewTable()
+	err := aliases.Add(&alias.Alias{
+		Name:      "opt",
+		Expansion: []string{"test-command", "test-option", "$1"},
+		Help:      "Set test option",
+	}, []string{"test-command", "another-command"})
+	if err != nil {
+		t.Fatalf("Unexpected failure adding alias: %s\n", err)
+	}
+	return aliases
+}
+
+func TestExpandAliases(t *testing.T) {
+	y := getYang(t, bytes.NewBufferString(fmt.Sprintf(schemaTemplate, schema_text)))
+	ctx := alias.NewContext(context.Background(), getAliases(t, y))
+
+	epath, err := y.ExpandContext(ctx, []string{"opt", "foo"}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected expand failure: %s\n", err)
+	}
+	if fmt.Sprint(epath) != "[test-command test-option foo]" {
+		t.Errorf("Unexpected expansion: %v\n", epath)
+	}
+
+	// The rewritten path is authorised
+	_, err = y.ExpandContext(ctx, []string{"opt", "foo"},
+		auth.Func(authoriseDenyTestCommand))
+	if _, ok := err.(*patherr.CommandInval); !ok {
+		t.Errorf("Expected alias expansion to be denied, got %v\n", err)
+	}
+
+	comps, err := y.CompletionContext(ctx, []string{}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected completion failure: %s\n", err)
+	}
+	if _, ok := comps["test-command"]; !ok || comps["opt"] != "Set test option" {
+		t.Errorf("Expected alias in completions: %v\n", comps)
+	}
+
+	// Matches are for the rewritten path
+	rpath, matches, err := y.ExpandMatchesContext(ctx, []string{"opt", "foo"}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected expand matches failure: %s\n", err)
+	}
+	if fmt.Sprint(rpath) != "[test-command test-option foo]" || len(matches) != 3 {
+		t.Errorf("Unexpected matches for %v: %v\n", rpath, matches)
+	}
+
+	// An alias shadowing a command of another source is refused
+	octx := alias.WithCommands(ctx, []string{"optimise"})
+	if _, err = y.ExpandContext(octx, []string{"opt", "foo"}, nil); err == nil {
+		t.Errorf("Expected alias shadowing another source to fail\n")
+	} else if _, ok := err.(*alias.ShadowError); !ok {
+		t.Errorf("Expected shadow error, got %v\n", err)
+	}
+	if _, _, err = y.ExpandMatchesContext(octx, []string{"opt", "foo"}, nil); err == nil {
+		t.Errorf("Expected alias shadowing another source to fail matching\n")
+	}
+}
@@ -0,0 +1,35 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package yang
+
+import (
+	"github.com/danos/config/schema"
+)
+
+// ExtensionsModule is the module, installed alongside the opd YANG,
+// defining the op-ext extensions read by this package. As with the
+// configd and opd extensions, models must import it with its own
+// prefix, op-ext.
+const ExtensionsModule = "vyatta-op-extensions-v1"
+
+const extensionsPrefix = "op-ext"
+
+// opExt returns the argument of the op-ext:keyword statement used on
+// sn, and whether the statement is present.
+func opExt(sn schema.Node, keyword string) (string, bool) {
+	for _, e := range sn.Exts() {
+		if e.Prefix == extensionsPrefix && e.Keyword == keyword {
+			return e.Argument, true
+		}
+	}
+	return "", false
+}
+
+// opExtArg returns the argument of the op-ext:keyword statement used on
+// sn, or "" if it is not present
+func opExtArg(sn schema.Node, keyword string) string {
+	arg, _ := opExt(sn, keyword)
+	return arg
+}
@@ -0,0 +1,170 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package yang
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/danos/config/schema"
+	"github.com/danos/yang/parse"
+)
+
+// nodeFeatures holds the if-feature statements of the opd nodes of a
+// set of modules, as module:feature, keyed by the node's schema path.
+// The compiled schema drops them once the disabled nodes are removed,
+// so they are recovered from the parsed modules.
+type nodeFeatures map[string][]string
+
+// featuresLoader parses the modules of a model set when their features
+// are first needed
+type featuresLoader struct {
+	once     sync.Once
+	load     func() map[string]*parse.Tree
+	features nodeFeatures
+}
+
+func (l *featuresLoader) get() nodeFeatures {
+	if l == nil {
+		return nil
+	}
+	l.once.Do(func() { l.features = collectFeatures(l.load()) })
+	return l.features
+}
+
+// dirModules returns a loader parsing the modules in dir which define
+// opd nodes. Modules which cannot be read or parsed are skipped, as
+// they can have contributed nothing to the compiled schema.
+func dirModules(dir string) *featuresLoader {
+	return &featuresLoader{load: func() map[string]*parse.Tree {
+		files, _ := filepath.Glob(filepath.Join(dir, "*.yang"))
+		modules := make(map[string]*parse.Tree)
+		for _, file := range files {
+			text, err := ioutil.ReadFile(file)
+			if err != nil || !strings.Contains(string(text), "opd:") {
+				continue
+			}
+			t, err := schema.Parse(file, string(text))
+			if err != nil {
+				continue
+			}
+			modules[t.Root.Argument().String()] = t
+		}
+		return modules
+	}}
+}
+
+// parsedModules returns a loader for modules already parsed
+func parsedModules(modules map[string]*parse.Tree) *featuresLoader {
+	return &featuresLoader{load: func() map[string]*parse.Tree {
+		return modules
+	}}
+}
+
+func collectFeatures(modules map[string]*parse.Tree) nodeFeatures {
+	features := make(nodeFeatures)
+	for _, t := range modules {
+		mod, prefixes := moduleScope(t.Root)
+		c := &featureCollector{mod: mod, prefixes: prefixes, features: features}
+		c.walk(t.Root, nil)
+	}
+	return features
+}
+
+// moduleScope returns the name of the module n defines, or that a
+// submodule belongs to, and the modules its prefixes refer to
+func moduleScope(n parse.Node) (string, map[string]string) {
+	mod := n.Argument().String()
+	prefixes := make(map[string]string)
+	for _, c := range n.Children() {
+		switch c.Type() {
+		case parse.NodePrefix:
+			prefixes[c.Argument().String()] = mod
+		case parse.NodeBelongsTo:
+			mod = c.Argument().String()
+			for _, p := range c.Children() {
+				if p.Type() == parse.NodePrefix {
+					prefixes[p.Argument().String()] = mod
+				}
+			}
+		case parse.NodeImport:
+			for _, p := range c.Children() {
+				if p.Type() == parse.NodePrefix {
+					prefixes[p.Argument().String()] = c.Argument().String()
+				}
+			}
+		}
+	}
+	return mod, prefixes
+}
+
+type featureCollector struct {
+	mod      string
+	prefixes map[string]string
+	features nodeFeatures
+}
+
+// walk records the features of the opd nodes below n, at path
+func (c *featureCollector) walk(n parse.Node, path []string) {
+	for _, ch := range n.Children() {
+		switch ch.Type() {
+		case parse.NodeOpdCommand, parse.NodeOpdOption, parse.NodeOpdArgument:
+			cpath := append(path[:len(path):len(path)], ch.Argument().String())
+			if fs := c.ifFeatures(ch); len(fs) > 0 {
+				c.features[strings.Join(cpath, " ")] = fs
+			}
+			c.walk(ch, cpath)
+		case parse.NodeOpdAugment:
+			c.walk(ch, augmentPath(ch.Argument().String()))
+		}
+	}
+}
+
+// ifFeatures returns the features named by the if-feature statements
+// of n, as module:feature
+func (c *featureCollector) ifFeatures(n parse.Node) []string {
+	var fs []string
+	for _, ch := range n.Children() {
+		if ch.Type() != parse.NodeIfFeature {
+			continue
+		}
+		name := ch.Argument().String()
+		mod := c.mod
+		if i := strings.IndexByte(name, ':'); i >= 0 {
+			if m, ok := c.prefixes[name[:i]]; ok {
+				mod = m
+			}
+			name = name[i+1:]
+		}
+		fs = append(fs, mod+":"+name)
+	}
+	return fs
+}
+
+// augmentPath returns the schema path of the target of opd:augment
+func augmentPath(target string) []string {
+	var path []string
+	for _, elem := range strings.Split(target, "/") {
+		if elem == "" {
+			continue
+		}
+		if i := strings.IndexByte(elem, ':'); i >= 0 {
+			elem = elem[i+1:]
+		}
+		path = append(path, elem)
+	}
+	return path
+}
+
+// schemaPath returns the key of sn in nodeFeatures
+func schemaPath(sn schema.Node) string {
+	var path []string
+	for ; sn != nil && sn.Parent() != nil; sn = sn.Parent() {
+		path = append([]string{sn.Name()}, path...)
+	}
+	return strings.Join(path, " ")
+}
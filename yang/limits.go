@@ -0,0 +1,50 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package yang
+
+import (
+	"github.com/danos/config/schema"
+	"github.com/danos/op/tmpl"
+)
+
+// setLimits sets the resource limits of template from the
+// op-ext:timeout, op-ext:cpu-limit, op-ext:memory-limit and
+// op-ext:output-limit statements used on sn or, for each limit sn does
+// not declare, the nearest enclosing node up to and including its
+// opd:command.
+func setLimits(template *tmpl.OpTmpl, sn schema.Node) {
+	var timeout, cpu, mem, output string
+	for n := sn; n != nil; n = n.Parent() {
+		timeout = firstSet(timeout, opExtArg(n, "timeout"))
+		cpu = firstSet(cpu, opExtArg(n, "cpu-limit"))
+		mem = firstSet(mem, opExtArg(n, "memory-limit"))
+		output = firstSet(output, opExtArg(n, "output-limit"))
+		if _, ok := n.(schema.OpdCommand); ok {
+			break
+		}
+	}
+
+	if v, err := tmpl.ParseTimeLimit(timeout); err == nil {
+		template.SetTimeout(v)
+	}
+	if v, err := tmpl.ParseTimeLimit(cpu); err == nil {
+		template.SetCPULimit(v)
+	}
+	if v, err := tmpl.ParseSizeLimit(mem); err == nil {
+		template.SetMemLimit(v)
+	}
+	if v, err := tmpl.ParseSizeLimit(output); err == nil {
+		template.SetOutputLimit(v)
+	}
+}
+
+func firstSet(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
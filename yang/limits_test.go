@@ -0,0 +1,62 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package yang
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetLimits(t *testing.T) {
+	y := getExtYang(t, `opd:command ping {
+			op-ext:timeout 60;
+			op-ext:cpu-limit 10;
+			op-ext:output-limit 1M;
+			opd:on-enter "ping";
+
+			opd:argument host {
+				op-ext:timeout 5s;
+				op-ext:memory-limit 64M;
+				opd:on-enter "ping $2";
+				type string;
+			}
+		}
+		opd:command uptime {
+			opd:on-enter "uptime";
+		}`)
+
+	template, err := y.TmplGet([]string{"ping", "host1"})
+	if err != nil {
+		t.Fatalf("Unexpected TmplGet failure: %s\n", err)
+	}
+	if template.Timeout() != 5*time.Second {
+		t.Errorf("Unexpected timeout: %v\n", template.Timeout())
+	}
+	if template.CPULimit() != 10*time.Second {
+		t.Errorf("Unexpected CPU limit: %v\n", template.CPULimit())
+	}
+	if template.MemLimit() != 64<<20 {
+		t.Errorf("Unexpected memory limit: %v\n", template.MemLimit())
+	}
+	if template.OutputLimit() != 1<<20 {
+		t.Errorf("Unexpected output limit: %v\n", template.OutputLimit())
+	}
+
+	template, err = y.TmplGet([]string{"ping"})
+	if err != nil {
+		t.Fatalf("Unexpected TmplGet failure: %s\n", err)
+	}
+	if template.Timeout() != time.Minute || template.MemLimit() != 0 {
+		t.Errorf("Unexpected command limits: %v\n", template.Map())
+	}
+
+	template, err = y.TmplGet([]string{"uptime"})
+	if err != nil {
+		t.Fatalf("Unexpected TmplGet failure: %s\n", err)
+	}
+	if template.Timeout() != 0 || template.OutputLimit() != 0 {
+		t.Errorf("Unexpected limits without extensions: %v\n", template.Map())
+	}
+}
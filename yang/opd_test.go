@@ -8,7 +8,10 @@
 package yang
 
 import (
+	"fmt"
+	"io/ioutil"
 	"strconv"
+	"testing"
 
 	"github.com/danos/config/schema"
 	"github.com/danos/yang/compile"
@@ -28,6 +31,23 @@ module test-configd-compile {
 }
 `
 
+// Schema Template importing the op-ext extensions, for use with
+// getExtYang.
+const extSchemaTemplate = `
+module test-configd-compile {
+	namespace "urn:vyatta.com:test:configd-compile";
+	prefix test;
+	import vyatta-op-extensions-v1 {
+		prefix op-ext;
+	}
+	organization "Brocade Communications Systems, Inc.";
+	revision 2014-12-29 {
+		description "Test schema for configd";
+	}
+	%s
+}
+`
+
 func GetTestYang(bufs ...[]byte) (*Yang, error) {
 
 	const name = "schema"
@@ -41,7 +61,23 @@ func GetTestYang(bufs ...[]byte) (*Yang, error) {
 		modules[mod] = t
 	}
 	st, err := schema.CompileModules(modules, "", false, compile.IsOpd, &schema.CompilationExtensions{})
-	return &Yang{stOpd: st}, err
+	return &Yang{stOpd: st, features: parsedModules(modules)}, err
+}
+
+// getExtYang compiles body, inserted into extSchemaTemplate, along with
+// the op-ext extensions module
+func getExtYang(t *testing.T, body string) *Yang {
+	t.Helper()
+
+	ext, err := ioutil.ReadFile(ExtensionsModule + ".yang")
+	if err != nil {
+		t.Fatalf("Unable to read extensions module:\n  %s\n\n", err.Error())
+	}
+	y, err := GetTestYang(ext, []byte(fmt.Sprintf(extSchemaTemplate, body)))
+	if err != nil {
+		t.Fatalf("Unexpected compilation failure:\n  %s\n\n", err.Error())
+	}
+	return y
 }
 
 func isInSlice(s []string, elem string) bool {
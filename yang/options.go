@@ -0,0 +1,127 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package yang
+
+import (
+	"context"
+
+	"github.com/danos/config/schema"
+	"github.com/danos/op/auth"
+)
+
+// isRepeatable reports whether sch carries the op-ext:repeatable
+// extension, allowing an option to be given more than once in a single
+// command.
+func isRepeatable(sch schema.Node) bool {
+	_, ok := opExt(sch, "repeatable")
+	return ok
+}
+
+// scopeOptions returns the options of the nodes enclosing sch, up to and
+// including the nearest opd:command. Once an option or argument has
+// been matched these remain available, so that the options of a
+// command may be given in any order.
+func scopeOptions(sch schema.Node) []schema.Node {
+	switch sch.(type) {
+	case schema.OpdOption, schema.OpdArgument:
+	default:
+		return nil
+	}
+
+	var opts []schema.Node
+	for a := sch.Parent(); a != nil; a = a.Parent() {
+		switch a.(type) {
+		case schema.OpdCommand, schema.OpdOption, schema.OpdArgument:
+		default:
+			return opts
+		}
+		for _, c := range a.OpdChildren() {
+			if _, ok := c.(schema.OpdOption); ok {
+				opts = append(opts, c)
+			}
+		}
+		if _, ok := a.(schema.OpdCommand); ok {
+			return opts
+		}
+	}
+	return opts
+}
+
+// appendScopeOptions adds the options enclosing sch to candidates,
+// skipping any whose name is already present.
+func appendScopeOptions(candidates []schema.Node, sch schema.Node) []schema.Node {
+	for _, opt := range scopeOptions(sch) {
+		present := false
+		for _, c := range candidates {
+			if c.Name() == opt.Name() {
+				present = true
+				break
+			}
+		}
+		if !present {
+			candidates = append(candidates, opt)
+		}
+	}
+	return candidates
+}
+
+// usedOptions records the options matched so far while expanding a
+// path. Options may only be given once unless marked repeatable.
+type usedOptions []schema.Node
+
+func (u *usedOptions) use(sch schema.Node) {
+	if _, ok := sch.(schema.OpdOption); ok {
+		*u = append(*u, sch)
+	}
+}
+
+func (u usedOptions) available(sch schema.Node) bool {
+	if _, ok := sch.(schema.OpdOption); !ok || isRepeatable(sch) {
+		return true
+	}
+	for _, n := range u {
+		if n == sch {
+			return false
+		}
+	}
+	return true
+}
+
+// ExpandOptions expands path as Expand does, additionally returning the
+// values given for each option, in the order they appeared. Options
+// which take no value have an empty string recorded for each time they
+// were given.
+func (y *Yang) ExpandOptions(
+	path []string,
+	a Authoriser,
+) ([]string, map[string][]string, error) {
+	epath, matches, err := y.expand(path,
+		newAuthoriser(context.Background(), auth.Expand, a.adapt()))
+	if _, ok := err.(*CommandIncomplete); err != nil && !ok {
+		return nil, nil, err
+	}
+
+	opts := make(map[string][]string)
+	for i, entry := range matches {
+		for _, m := range entry {
+			em, ok := m.(expandMatch)
+			if !ok {
+				continue
+			}
+			if _, ok := em.node.(schema.OpdOption); !ok {
+				continue
+			}
+			switch {
+			case em.isarg:
+				opts[em.Name()] = append(opts[em.Name()], epath[i])
+			case em.Name() == epath[i]:
+				if _, ok := em.node.Type().(schema.Empty); ok {
+					opts[em.Name()] = append(opts[em.Name()], "")
+				}
+			}
+		}
+	}
+	return epath, opts, err
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package yang
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/danos/utils/pathutil"
+)
+
+const optionsSchema = `opd:command show {
+			opd:command log {
+				opd:on-enter "show-log";
+
+				opd:option tail {
+					type uint32;
+				}
+				opd:option file {
+					type string;
+				}
+				opd:option reverse {
+					type empty;
+				}
+			}
+		}`
+
+func checkExpandOptions(
+	t *testing.T,
+	y *Yang,
+	path string,
+	expect []string,
+	expectOpts map[string][]string,
+) {
+	t.Helper()
+
+	expands, opts, err := y.ExpandOptions(pathutil.Makepath(path), nil)
+	if err != nil {
+		t.Errorf("Unexpected expand failure for '%s':\n  %s\n\n", path, err.Error())
+		return
+	}
+	if len(expands) != len(expect) {
+		t.Errorf("Expansion doesn't match expected:\n Expected - %v\n Got - %v\n",
+			expect, expands)
+	}
+	for i := range expect {
+		if i < len(expands) && expands[i] != expect[i] {
+			t.Errorf("Expansion doesn't match expected:\n Expected - %v\n Got - %v\n",
+				expect, expands)
+			break
+		}
+	}
+	if len(opts) != len(expectOpts) {
+		t.Errorf("Options don't match expected:\n Expected - %v\n Got - %v\n",
+			expectOpts, opts)
+		return
+	}
+	for name, vals := range expectOpts {
+		got := opts[name]
+		if len(got) != len(vals) {
+			t.Errorf("Values for option %s don't match expected:\n Expected - %v\n Got - %v\n",
+				name, vals, got)
+			continue
+		}
+		for i := range vals {
+			if got[i] != vals[i] {
+				t.Errorf("Values for option %s don't match expected:\n Expected - %v\n Got - %v\n",
+					name, vals, got)
+				break
+			}
+		}
+	}
+}
+
+func TestExpandOptionsAnyOrder(t *testing.T) {
+	y := getYang(t, bytes.NewBufferString(fmt.Sprintf(schemaTemplate, optionsSchema)))
+
+	checkExpandOptions(t, y, "show/log/tail/50/file/messages",
+		[]string{"show", "log", "tail", "50", "file", "messages"},
+		map[string][]string{
+			"tail": {"50"},
+			"file": {"messages"},
+		})
+	checkExpandOptions(t, y, "sh/l/f/messages/t/50",
+		[]string{"show", "log", "file", "messages", "tail", "50"},
+		map[string][]string{
+			"tail": {"50"},
+			"file": {"messages"},
+		})
+	checkExpandOptions(t, y, "sh/l/r/f/messages",
+		[]string{"show", "log", "reverse", "file", "messages"},
+		map[string][]string{
+			"reverse": {""},
+			"file":    {"messages"},
+		})
+	checkExpandOptions(t, y, "sh/l/t/10/r",
+		[]string{"show", "log", "tail", "10", "reverse"},
+		map[string][]string{
+			"tail":    {"10"},
+			"reverse": {""},
+		})
+}
+
+func TestExpandOptionsNotRepeatable(t *testing.T) {
+	schema_text := bytes.NewBufferString(fmt.Sprintf(schemaTemplate, optionsSchema))
+
+	checkExpandInvalid(t, schema_text, "/show/log/tail/50/tail/20",
+		[]string{"show", "log", "tail", "50"}, "tail")
+	checkExpandInvalid(t, schema_text, "/show/log/file/a/t/5/file/b",
+		[]string{"show", "log", "file", "a", "tail", "5"}, "file")
+}
+
+func TestExpandOptionsRepeatable(t *testing.T) {
+	y := getExtYang(t, `opd:command show {
+			opd:command log {
+				opd:on-enter "show-log";
+
+				opd:option match {
+					op-ext:repeatable;
+					type string;
+				}
+				opd:option tail {
+					type uint32;
+				}
+			}
+		}`)
+
+	checkExpandOptions(t, y, "show/log/match/foo/match/bar",
+		[]string{"show", "log", "match", "foo", "match", "bar"},
+		map[string][]string{
+			"match": {"foo", "bar"},
+		})
+	checkExpandOptions(t, y, "sh/l/m/foo/t/5/m/bar/m/baz",
+		[]string{"show", "log", "match", "foo", "tail", "5", "match", "bar",
+			"match", "baz"},
+		map[string][]string{
+			"match": {"foo", "bar", "baz"},
+			"tail":  {"5"},
+		})
+
+	// Only the option marked repeatable may be given again
+	_, _, err := y.ExpandOptions(
+		pathutil.Makepath("show/log/match/foo/tail/5/tail/6"), nil)
+	if err == nil {
+		t.Errorf("Expected repeated tail option to be invalid\n")
+	}
+
+	// Validation agrees with expansion
+	if ok, err := y.TmplValidateValues(
+		pathutil.Makepath("show/log/tail/5/tail/6")); ok || err == nil {
+		t.Errorf("Expected repeated tail option to fail validation\n")
+	}
+	if ok, err := y.TmplValidateValues(
+		pathutil.Makepath("show/log/match/foo/tail/5/match/bar")); !ok {
+		t.Errorf("Unexpected validation failure: %v\n", err)
+	}
+}
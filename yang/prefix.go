@@ -0,0 +1,324 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package yang
+
+import (
+	"math/big"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/danos/config/schema"
+)
+
+// PrefixStatus describes how a partially typed token relates to the
+// constraints of the node it is being entered for.
+type PrefixStatus int
+
+const (
+	// PrefixInvalid means no amount of further typing can make the
+	// token valid.
+	PrefixInvalid PrefixStatus = iota
+	// PrefixIncomplete means the token is not valid yet, but could
+	// still become valid if more characters are typed.
+	PrefixIncomplete
+	// PrefixValid means the token is already complete and valid.
+	PrefixValid
+)
+
+func (s PrefixStatus) String() string {
+	switch s {
+	case PrefixInvalid:
+		return "invalid"
+	case PrefixIncomplete:
+		return "incomplete"
+	case PrefixValid:
+		return "valid"
+	}
+	return "unknown"
+}
+
+func bestStatus(a, b PrefixStatus) PrefixStatus {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func worstStatus(a, b PrefixStatus) PrefixStatus {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// TmplValidatePrefix reports whether partial, typed as the token
+// following path, is already invalid, could still become valid or is
+// complete and valid. Keywords are matched by prefix; values are
+// checked against the pattern, length, range and enumeration
+// constraints of their type.
+func (y *Yang) TmplValidatePrefix(path []string, partial string) (PrefixStatus, error) {
+	if y.stOpd == nil {
+		return PrefixInvalid, nil
+	}
+
+	var sn schema.Node = y.stOpd
+	val := false
+	if len(path) > 0 {
+		tmpl, err := y.opdSchemaPathDescendant(path)
+		if err != nil {
+			return PrefixInvalid, err
+		}
+		sn, val = tmpl.Node, tmpl.Val
+	}
+
+	// An option that takes a value must be followed by that value
+	if _, ok := sn.(schema.OpdOption); ok && !val {
+		if _, ok := sn.Type().(schema.Empty); !ok {
+			return y.valuePrefixStatus(path, partial, sn.Type()), nil
+		}
+	}
+
+	children := sn.OpdChildren()
+	args := sn.Arguments()
+	if len(children) == 0 && sn.Parent() != nil {
+		children = sn.Parent().OpdChildren()
+		args = sn.Parent().Arguments()
+	}
+
+	status := PrefixInvalid
+	for _, c := range children {
+		if isElemOf(args, c.Name()) {
+			status = bestStatus(status,
+				y.valuePrefixStatus(path, partial, c.Type()))
+			continue
+		}
+		status = bestStatus(status, keywordStatus(c.Name(), partial))
+	}
+	return status, nil
+}
+
+func keywordStatus(keyword, partial string) PrefixStatus {
+	switch {
+	case keyword == partial:
+		return PrefixValid
+	case strings.HasPrefix(keyword, partial):
+		return PrefixIncomplete
+	}
+	return PrefixInvalid
+}
+
+func (y *Yang) valuePrefixStatus(
+	path []string,
+	partial string,
+	ty schema.Type,
+) PrefixStatus {
+	switch v := ty.(type) {
+	case schema.Empty:
+		return PrefixInvalid
+	case schema.Union:
+		status := PrefixInvalid
+		for _, t := range v.Typs() {
+			status = bestStatus(status, y.valuePrefixStatus(path, partial, t))
+		}
+		return status
+	case schema.Enumeration:
+		status := PrefixInvalid
+		for _, e := range v.Enums() {
+			status = bestStatus(status, keywordStatus(e.Val, partial))
+		}
+		return status
+	case schema.Boolean:
+		return bestStatus(keywordStatus("true", partial),
+			keywordStatus("false", partial))
+	case schema.Integer:
+		rngs := make([]bigRange, 0, len(v.Range()))
+		for _, r := range v.Range() {
+			rngs = append(rngs, bigRange{
+				lo: big.NewInt(r.Start), hi: big.NewInt(r.End)})
+		}
+		return numberStatus(partial, rngs)
+	case schema.Uinteger:
+		rngs := make([]bigRange, 0, len(v.Range()))
+		for _, r := range v.Range() {
+			rngs = append(rngs, bigRange{
+				lo: new(big.Int).SetUint64(r.Start),
+				hi: new(big.Int).SetUint64(r.End)})
+		}
+		return numberStatus(partial, rngs)
+	case schema.String:
+		status := PrefixValid
+		if l := v.Len(); l != nil {
+			status = lengthStatus(utf8.RuneCountInString(partial), l.Lbs)
+		}
+		for _, pats := range v.Pats() {
+			for _, re := range pats {
+				status = worstStatus(status, patternStatus(re, partial))
+			}
+		}
+		return status
+	}
+
+	// No constraints we can check incrementally; fall back to
+	// validating the complete value.
+	if ok, _ := y.TmplValidateValues(append(path[:len(path):len(path)], partial)); ok {
+		return PrefixValid
+	}
+	return PrefixIncomplete
+}
+
+func lengthStatus(n int, lbs schema.LbSlice) PrefixStatus {
+	if len(lbs) == 0 {
+		return PrefixValid
+	}
+	status := PrefixInvalid
+	for _, lb := range lbs {
+		switch {
+		case uint64(n) >= lb.Start && uint64(n) <= lb.End:
+			return PrefixValid
+		case uint64(n) < lb.Start:
+			status = PrefixIncomplete
+		}
+	}
+	return status
+}
+
+type bigRange struct {
+	lo, hi *big.Int
+}
+
+// Longest decimal representation of a 64 bit integer
+const maxDigits = 20
+
+// numberStatus determines whether partial, or any number that can be
+// formed by appending digits to it, lies within one of rngs.
+func numberStatus(partial string, rngs []bigRange) PrefixStatus {
+	if len(rngs) == 0 {
+		return PrefixInvalid
+	}
+
+	digits := partial
+	neg := strings.HasPrefix(digits, "-")
+	if neg {
+		digits = digits[1:]
+	}
+	if strings.Trim(digits, "0123456789") != "" {
+		return PrefixInvalid
+	}
+	if digits == "" {
+		for _, r := range rngs {
+			if !neg || r.lo.Sign() < 0 {
+				return PrefixIncomplete
+			}
+		}
+		return PrefixInvalid
+	}
+
+	v, _ := new(big.Int).SetString(digits, 10)
+	ten := big.NewInt(10)
+	scale := big.NewInt(1)
+	for j := 0; len(digits)+j <= maxDigits; j++ {
+		// Appending j digits gives a value in [v*10^j, v*10^j+10^j-1]
+		lo := new(big.Int).Mul(v, scale)
+		hi := new(big.Int).Add(lo, scale)
+		hi.Sub(hi, big.NewInt(1))
+		if neg {
+			lo, hi = hi.Neg(hi), lo.Neg(lo)
+		}
+		for _, r := range rngs {
+			if j == 0 {
+				if lo.Cmp(r.lo) >= 0 && lo.Cmp(r.hi) <= 0 {
+					return PrefixValid
+				}
+				continue
+			}
+			if hi.Cmp(r.lo) >= 0 && lo.Cmp(r.hi) <= 0 {
+				return PrefixIncomplete
+			}
+		}
+		scale.Mul(scale, ten)
+	}
+	return PrefixInvalid
+}
+
+// patternStatus runs the pattern as an anchored NFA over partial and
+// reports whether it matched, is still able to match given more input,
+// or has no live threads left.
+func patternStatus(re *regexp.Regexp, partial string) PrefixStatus {
+	rx, err := syntax.Parse(re.String(), syntax.Perl)
+	if err != nil {
+		return PrefixIncomplete
+	}
+	prog, err := syntax.Compile(rx.Simplify())
+	if err != nil {
+		return PrefixIncomplete
+	}
+
+	var addThread func(set map[uint32]bool, pc uint32, atStart, atEnd bool)
+	addThread = func(set map[uint32]bool, pc uint32, atStart, atEnd bool) {
+		if set[pc] {
+			return
+		}
+		set[pc] = true
+		inst := &prog.Inst[pc]
+		switch inst.Op {
+		case syntax.InstAlt, syntax.InstAltMatch:
+			addThread(set, inst.Out, atStart, atEnd)
+			addThread(set, inst.Arg, atStart, atEnd)
+		case syntax.InstCapture, syntax.InstNop:
+			addThread(set, inst.Out, atStart, atEnd)
+		case syntax.InstEmptyWidth:
+			op := syntax.EmptyOp(inst.Arg)
+			if op&(syntax.EmptyBeginLine|syntax.EmptyBeginText) != 0 && !atStart {
+				return
+			}
+			if op&(syntax.EmptyEndLine|syntax.EmptyEndText) != 0 && !atEnd {
+				return
+			}
+			addThread(set, inst.Out, atStart, atEnd)
+		}
+	}
+
+	runes := []rune(partial)
+	clist := make(map[uint32]bool)
+	addThread(clist, uint32(prog.Start), true, len(runes) == 0)
+	for i, r := range runes {
+		nlist := make(map[uint32]bool)
+		for pc := range clist {
+			inst := &prog.Inst[pc]
+			matched := false
+			switch inst.Op {
+			case syntax.InstRune:
+				matched = inst.MatchRune(r)
+			case syntax.InstRune1:
+				matched = r == inst.Rune[0]
+			case syntax.InstRuneAny:
+				matched = true
+			case syntax.InstRuneAnyNotNL:
+				matched = r != '\n'
+			}
+			if matched {
+				addThread(nlist, inst.Out, false, i == len(runes)-1)
+			}
+		}
+		if len(nlist) == 0 {
+			return PrefixInvalid
+		}
+		clist = nlist
+	}
+
+	status := PrefixInvalid
+	for pc := range clist {
+		switch prog.Inst[pc].Op {
+		case syntax.InstMatch:
+			return PrefixValid
+		case syntax.InstRune, syntax.InstRune1,
+			syntax.InstRuneAny, syntax.InstRuneAnyNotNL:
+			status = PrefixIncomplete
+		}
+	}
+	return status
+}
@@ -0,0 +1,175 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package yang
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"regexp"
+	"testing"
+
+	"github.com/danos/utils/pathutil"
+)
+
+func checkPrefix(
+	t *testing.T,
+	y *Yang,
+	path string,
+	partial string,
+	expect PrefixStatus,
+) {
+	t.Helper()
+
+	status, err := y.TmplValidatePrefix(pathutil.Makepath(path), partial)
+	if err != nil {
+		t.Errorf("Unexpected prefix validation failure:\n  %s\n\n", err.Error())
+		return
+	}
+	if status != expect {
+		t.Errorf("Prefix '%s' after '%s':\n  Expected - %s\n  Got - %s\n",
+			partial, path, expect, status)
+	}
+}
+
+func TestPrefixValidate(t *testing.T) {
+	schema_text := bytes.NewBufferString(fmt.Sprintf(
+		schemaTemplate,
+		`opd:command test-command {
+			opd:option test-uint8 {
+				type uint8 {
+					range 10..200;
+				}
+			}
+			opd:option test-int8 {
+				type int8 {
+					range -20..-5;
+				}
+			}
+			opd:option test-length {
+				type string {
+					length 3..5;
+				}
+			}
+			opd:option test-pattern {
+				type string {
+					pattern 'ab[0-9]+';
+				}
+			}
+			opd:option test-bool {
+				type boolean;
+			}
+			opd:argument test-arg {
+				type enumeration {
+					enum one;
+					enum two;
+					enum three;
+				}
+			}
+		}`))
+
+	y, err := GetTestYang(schema_text.Bytes())
+	if err != nil {
+		t.Fatalf("Unexpected compilation failure:\n  %s\n\n", err.Error())
+	}
+
+	checkPrefix(t, y, "", "test", PrefixIncomplete)
+	checkPrefix(t, y, "", "test-command", PrefixValid)
+	checkPrefix(t, y, "", "bogus", PrefixInvalid)
+
+	checkPrefix(t, y, "test-command", "test-u", PrefixIncomplete)
+	checkPrefix(t, y, "test-command", "t", PrefixIncomplete)
+	checkPrefix(t, y, "test-command", "one", PrefixValid)
+	checkPrefix(t, y, "test-command", "fou", PrefixInvalid)
+
+	checkPrefix(t, y, "test-command/test-uint8", "", PrefixIncomplete)
+	checkPrefix(t, y, "test-command/test-uint8", "2", PrefixIncomplete)
+	checkPrefix(t, y, "test-command/test-uint8", "20", PrefixValid)
+	checkPrefix(t, y, "test-command/test-uint8", "201", PrefixInvalid)
+	checkPrefix(t, y, "test-command/test-uint8", "3", PrefixIncomplete)
+	checkPrefix(t, y, "test-command/test-uint8", "30", PrefixValid)
+	checkPrefix(t, y, "test-command/test-uint8", "300", PrefixInvalid)
+	checkPrefix(t, y, "test-command/test-uint8", "-", PrefixInvalid)
+	checkPrefix(t, y, "test-command/test-uint8", "1x", PrefixInvalid)
+
+	checkPrefix(t, y, "test-command/test-int8", "-", PrefixIncomplete)
+	checkPrefix(t, y, "test-command/test-int8", "-1", PrefixIncomplete)
+	checkPrefix(t, y, "test-command/test-int8", "-15", PrefixValid)
+	checkPrefix(t, y, "test-command/test-int8", "-3", PrefixInvalid)
+	checkPrefix(t, y, "test-command/test-int8", "5", PrefixInvalid)
+
+	checkPrefix(t, y, "test-command/test-length", "ab", PrefixIncomplete)
+	checkPrefix(t, y, "test-command/test-length", "abcd", PrefixValid)
+	checkPrefix(t, y, "test-command/test-length", "abcdef", PrefixInvalid)
+
+	checkPrefix(t, y, "test-command/test-pattern", "a", PrefixIncomplete)
+	checkPrefix(t, y, "test-command/test-pattern", "ab", PrefixIncomplete)
+	checkPrefix(t, y, "test-command/test-pattern", "ab12", PrefixValid)
+	checkPrefix(t, y, "test-command/test-pattern", "ac", PrefixInvalid)
+
+	checkPrefix(t, y, "test-command/test-bool", "tr", PrefixIncomplete)
+	checkPrefix(t, y, "test-command/test-bool", "false", PrefixValid)
+	checkPrefix(t, y, "test-command/test-bool", "yes", PrefixInvalid)
+}
+
+func TestPatternStatus(t *testing.T) {
+	tests := []struct {
+		pattern string
+		partial string
+		expect  PrefixStatus
+	}{
+		{"^[a-z]+[0-9]$", "", PrefixIncomplete},
+		{"^[a-z]+[0-9]$", "abc", PrefixIncomplete},
+		{"^[a-z]+[0-9]$", "abc1", PrefixValid},
+		{"^[a-z]+[0-9]$", "abc12", PrefixInvalid},
+		{"^[a-z]+[0-9]$", "1", PrefixInvalid},
+		{"eth[0-9]+", "et", PrefixIncomplete},
+		{"eth[0-9]+", "eth0", PrefixValid},
+		{"eth[0-9]+", "dp0", PrefixInvalid},
+		{"(ab|cd)*", "", PrefixValid},
+		{"(ab|cd)*", "abc", PrefixIncomplete},
+		{"(ab|cd)*", "abcd", PrefixValid},
+	}
+
+	for _, test := range tests {
+		re := regexp.MustCompile(test.pattern)
+		if got := patternStatus(re, test.partial); got != test.expect {
+			t.Errorf("Pattern '%s' with '%s':\n  Expected - %s\n  Got - %s\n",
+				test.pattern, test.partial, test.expect, got)
+		}
+	}
+}
+
+func TestNumberStatus(t *testing.T) {
+	rngs := []bigRange{
+		{lo: big.NewInt(-300), hi: big.NewInt(-250)},
+		{lo: big.NewInt(7), hi: big.NewInt(7)},
+		{lo: big.NewInt(1000), hi: big.NewInt(1999)},
+	}
+	tests := []struct {
+		partial string
+		expect  PrefixStatus
+	}{
+		{"", PrefixIncomplete},
+		{"-", PrefixIncomplete},
+		{"-2", PrefixIncomplete},
+		{"-25", PrefixIncomplete},
+		{"-260", PrefixValid},
+		{"-31", PrefixInvalid},
+		{"7", PrefixValid},
+		{"1", PrefixIncomplete},
+		{"1500", PrefixValid},
+		{"2", PrefixInvalid},
+		{"15000", PrefixInvalid},
+		{"a", PrefixInvalid},
+	}
+
+	for _, test := range tests {
+		if got := numberStatus(test.partial, rngs); got != test.expect {
+			t.Errorf("Number '%s':\n  Expected - %s\n  Got - %s\n",
+				test.partial, test.expect, got)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package yang
+
+import (
+	"context"
+
+	"github.com/danos/config/schema"
+	"github.com/danos/op/auth"
+	"github.com/danos/op/tmpl"
+)
+
+// isSecret reports whether values bound to sch hold sensitive
+// information
+func isSecret(sch schema.Node) bool {
+	switch v := sch.(type) {
+	case schema.OpdOption:
+		return v.Secret()
+	case schema.OpdArgument:
+		return v.Secret()
+	}
+	return false
+}
+
+// Resolve expands path and returns the template to run along with the
+// value the user gave for each argument and option in the path.
+func (y *Yang) Resolve(path []string, a Authoriser) (*tmpl.Invocation, error) {
+	return y.ResolveContext(context.Background(), path, a.adapt())
+}
+
+// ResolveContext is Resolve for the user carried by ctx, authorising
+// the path for execution.
+func (y *Yang) ResolveContext(
+	ctx context.Context,
+	path []string,
+	a auth.Authoriser,
+) (*tmpl.Invocation, error) {
+	if y.stOpd == nil {
+		return nil, nil
+	}
+	epath, matches, err := y.expand(path, newAuthoriser(ctx, auth.Execute, a))
+	if err != nil {
+		return nil, err
+	}
+	template, err := y.TmplGet(epath)
+	if err != nil {
+		return nil, err
+	}
+
+	inv := &tmpl.Invocation{Path: epath, Template: template}
+	for i, entry := range matches {
+		for _, m := range entry {
+			em, ok := m.(expandMatch)
+			if !ok || !em.isarg {
+				continue
+			}
+			inv.Bindings = append(inv.Bindings, tmpl.Binding{
+				Name:   em.Name(),
+				Value:  epath[i],
+				Type:   typeName(em.node.Type()),
+				Secret: isSecret(em.node),
+				Index:  i,
+			})
+			break
+		}
+	}
+	return inv, nil
+}
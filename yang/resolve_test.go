@@ -0,0 +1,66 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package yang
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/danos/op/tmpl"
+	"github.com/danos/utils/pathutil"
+)
+
+func checkBindings(t *testing.T, inv *tmpl.Invocation, expect []tmpl.Binding) {
+	t.Helper()
+
+	if len(inv.Bindings) != len(expect) {
+		t.Fatalf("Bindings don't match expected:\n Expected - %v\n Got - %v\n",
+			expect, inv.Bindings)
+	}
+	for i, b := range expect {
+		if inv.Bindings[i] != b {
+			t.Errorf("Binding %d doesn't match expected:\n Expected - %v\n Got - %v\n",
+				i, b, inv.Bindings[i])
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	y := getYang(t, bytes.NewBufferString(fmt.Sprintf(
+		schemaTemplate,
+		`opd:command set-password {
+			opd:on-enter "set-password";
+
+			opd:argument user {
+				type string;
+
+				opd:option password {
+					opd:secret true;
+					type string;
+				}
+				opd:option retries {
+					type uint32;
+				}
+			}
+		}`)))
+
+	inv, err := y.Resolve(pathutil.Makepath("set/fred/pass/s3cret/r/3"), nil)
+	if err != nil {
+		t.Fatalf("Unexpected resolve failure:\n  %s\n\n", err.Error())
+	}
+	if inv.Template.Run() != "set-password" {
+		t.Errorf("Unexpected run: %s\n", inv.Template.Run())
+	}
+	checkBindings(t, inv, []tmpl.Binding{
+		{Name: "user", Value: "fred", Type: "txt", Index: 1},
+		{Name: "password", Value: "s3cret", Type: "txt", Secret: true, Index: 3},
+		{Name: "retries", Value: "3", Type: "u32", Index: 5},
+	})
+
+	if _, err := y.Resolve(pathutil.Makepath("set/fred/foo"), nil); err == nil {
+		t.Errorf("Expected resolve failure for invalid path\n")
+	}
+}
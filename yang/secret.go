@@ -0,0 +1,73 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package yang
+
+import (
+	"context"
+
+	"github.com/danos/op/auth"
+	"github.com/danos/op/tmpl/tree"
+	"github.com/danos/utils/pathutil"
+)
+
+// SecretMatch is implemented by Matches which know whether the value
+// they match is sensitive. Argument values matched by a SecretMatch
+// reporting true are masked in error messages.
+type SecretMatch interface {
+	Match
+	IsSecret() bool
+}
+
+func (e expandMatch) IsSecret() bool {
+	return isSecret(e.node)
+}
+
+// entrySecret reports whether the element matched by entry holds a
+// secret value
+func entrySecret(entry []Match) bool {
+	for _, m := range entry {
+		if !m.IsArg() {
+			continue
+		}
+		if sm, ok := m.(SecretMatch); ok && sm.IsSecret() {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPathAttrs returns the attributes of the first n elements of the
+// path matched by matches
+func matchPathAttrs(matches [][]Match, n int) *pathutil.PathAttrs {
+	attrs := pathutil.NewPathAttrs()
+	for i := 0; i < n; i++ {
+		attr := pathutil.NewPathElementAttrs()
+		attr.Secret = i < len(matches) && entrySecret(matches[i])
+		attrs.Attrs = append(attrs.Attrs, attr)
+	}
+	return &attrs
+}
+
+// maskPath returns a copy of path with secret elements replaced, as
+// done by tree.Path.StringByAttrs
+func maskPath(path []string, attrs *pathutil.PathAttrs) []string {
+	masked := make([]string, len(path))
+	for i, v := range path {
+		if attrs != nil && i < len(attrs.Attrs) && attrs.Attrs[i].Secret {
+			v = tree.SecretMask
+		}
+		masked[i] = v
+	}
+	return masked
+}
+
+// PathAttrs returns the attributes of each element of path, which may
+// be abbreviated as for Expand. Values of secret options and arguments
+// are marked secret. Elements which cannot be matched are not.
+func (y *Yang) PathAttrs(path []string) *pathutil.PathAttrs {
+	matches := y.expandMatches(path,
+		newAuthoriser(context.Background(), auth.Expand, nil))
+	return matchPathAttrs(matches, len(path))
+}
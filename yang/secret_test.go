@@ -0,0 +1,169 @@
+// Copyright (c) 2019, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package yang
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/danos/op/tmpl/tree"
+	"github.com/danos/utils/patherr"
+	"github.com/danos/utils/pathutil"
+)
+
+const secretSchema = `opd:command set-password {
+			opd:on-enter "set-password";
+
+			opd:argument user {
+				type string;
+
+				opd:option password {
+					opd:secret true;
+					type string;
+				}
+				opd:option retries {
+					type uint32;
+				}
+			}
+		}
+		opd:command login {
+			opd:argument key {
+				opd:secret true;
+				type string;
+
+				opd:command now {
+					opd:on-enter "login-now";
+				}
+				opd:command later {
+					opd:on-enter "login-later";
+				}
+			}
+		}`
+
+func checkNoSecret(t *testing.T, err error, secret string) {
+	t.Helper()
+
+	if err == nil {
+		t.Fatalf("Expected an error\n")
+	}
+	if strings.Contains(err.Error(), secret) {
+		t.Errorf("Secret revealed in error:\n  %s\n", err)
+	}
+	if !strings.Contains(err.Error(), "****") {
+		t.Errorf("Secret not masked in error:\n  %s\n", err)
+	}
+}
+
+func TestExpandMasksSecrets(t *testing.T) {
+	y := getYang(t, bytes.NewBufferString(fmt.Sprintf(schemaTemplate, secretSchema)))
+
+	_, err := y.Expand(pathutil.Makepath("set/fred/pass/s3cret/bogus"), nil)
+	checkNoSecret(t, err, "s3cret")
+	inval, ok := err.(*patherr.CommandInval)
+	if !ok {
+		t.Fatalf("Unexpected error type: %T\n", err)
+	}
+	if strings.Join(inval.Path, " ") != "set-password fred password ****" {
+		t.Errorf("Unexpected error path: %v\n", inval.Path)
+	}
+
+	epath, err := y.Expand(pathutil.Makepath("login/hunter2"), nil)
+	checkNoSecret(t, err, "hunter2")
+	if _, ok := err.(*CommandIncomplete); !ok {
+		t.Fatalf("Unexpected error type: %T\n", err)
+	}
+	if epath[1] != "hunter2" {
+		t.Errorf("Expanded path should hold the real value: %v\n", epath)
+	}
+}
+
+type testMatch struct {
+	name   string
+	isarg  bool
+	secret bool
+}
+
+func (m testMatch) Name() string   { return m.name }
+func (m testMatch) Help() string   { return "" }
+func (m testMatch) IsArg() bool    { return m.isarg }
+func (m testMatch) IsSecret() bool { return m.secret }
+
+func TestValidateMasksSecrets(t *testing.T) {
+	y := getYang(t, bytes.NewBufferString(fmt.Sprintf(schemaTemplate, secretSchema)))
+
+	path := pathutil.Makepath("set-password/fred/password/s3cret/password/again")
+	_, err := y.opdWalk(path, noWalk)
+	inval, ok := err.(*patherr.PathInval)
+	if !ok {
+		t.Fatalf("Unexpected error: %v\n", err)
+	}
+	if strings.Join(inval.Path, " ") != "set-password fred password ****" {
+		t.Errorf("Unexpected error path: %v\n", inval.Path)
+	}
+
+	if ok, err := y.TmplValidateValues(path); ok || err == nil {
+		t.Fatalf("Expected validation failure\n")
+	} else if strings.Contains(err.Error(), "s3cret") {
+		t.Errorf("Secret revealed in error:\n  %s\n", err)
+	}
+
+	_, err = y.TmplGet(pathutil.Makepath("set-password/fred/password/s3cret/bogus"))
+	if err == nil {
+		t.Fatalf("Expected TmplGet failure\n")
+	}
+	if strings.Contains(err.Error(), "s3cret") {
+		t.Errorf("Secret revealed in error:\n  %s\n", err)
+	}
+}
+
+func TestProcessMatchesMasksSecrets(t *testing.T) {
+	path := []string{"login", "hunter2", "l"}
+	matches := [][]Match{
+		{testMatch{name: "login"}},
+		{testMatch{name: "key", isarg: true, secret: true}},
+		{testMatch{name: "later"}, testMatch{name: "last"}},
+	}
+
+	_, err := ProcessMatches(path, matches)
+	ambig, ok := err.(*patherr.PathAmbig)
+	if !ok {
+		t.Fatalf("Unexpected error: %v\n", err)
+	}
+	if strings.Join(ambig.Path, " ") != "login ****" {
+		t.Errorf("Unexpected error path: %v\n", ambig.Path)
+	}
+}
+
+func TestYangPathAttrs(t *testing.T) {
+	y := getYang(t, bytes.NewBufferString(fmt.Sprintf(schemaTemplate, secretSchema)))
+
+	tests := []struct {
+		path   string
+		expect string
+	}{
+		{"set-password/fred/password/s3cret/retries/3",
+			"set-password fred password **** retries 3"},
+		{"set/fred/pass/s3cret", "set fred pass ****"},
+		{"login/hunter2/now", "login **** now"},
+		{"login/hunter2/bogus/extra", "login **** bogus extra"},
+		{"bogus/s3cret", "bogus s3cret"},
+	}
+
+	for _, test := range tests {
+		path := pathutil.Makepath(test.path)
+		attrs := y.PathAttrs(path)
+		if len(attrs.Attrs) != len(path) {
+			t.Errorf("Expected %d attributes for %v, got %d\n",
+				len(path), path, len(attrs.Attrs))
+			continue
+		}
+		if s := tree.Path(path).StringByAttrs(attrs); s != test.expect {
+			t.Errorf("Unexpected masked path:\n Expected - %s\n Got - %s\n",
+				test.expect, s)
+		}
+	}
+}
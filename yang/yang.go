@@ -8,12 +8,16 @@
 package yang
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
 	"github.com/danos/config/schema"
 	"github.com/danos/config/yangconfig"
 	"github.com/danos/mgmterror"
+	"github.com/danos/op/alias"
+	"github.com/danos/op/auth"
+	"github.com/danos/op/matching"
 	"github.com/danos/op/tmpl"
 	"github.com/danos/utils/patherr"
 	"github.com/danos/utils/pathutil"
@@ -21,22 +25,12 @@ import (
 )
 
 type Yang struct {
-	stOpd schema.ModelSet
+	stOpd    schema.ModelSet
+	features *featuresLoader
 }
 
 type Authoriser func(path []string) (bool, error)
 
-func permitted(permitted bool, err error) bool {
-	return permitted && err == nil
-}
-
-func authorise(path []string, child string, auth Authoriser) (bool, error) {
-	if auth != nil {
-		return auth(append(path, child))
-	}
-	return true, nil
-}
-
 func isElemOf(list []string, elem string) bool {
 	for _, v := range list {
 		if v == elem {
@@ -46,11 +40,85 @@ func isElemOf(list []string, elem string) bool {
 	return false
 }
 
+// firstArgument returns the name of the positional argument that the
+// first value following sch binds to, if any.
+func firstArgument(sch schema.Node) string {
+	if args := sch.Arguments(); len(args) > 0 {
+		return args[0]
+	}
+	return ""
+}
+
+// isLeafArgument reports whether sch is an argument with no opd
+// children of its own, in which case any following elements are
+// matched against its parent's children.
+func isLeafArgument(sch schema.Node) bool {
+	if _, ok := sch.(schema.OpdArgument); !ok {
+		return false
+	}
+	return sch.Parent() != nil && len(sch.OpdChildren()) == 0
+}
+
+// argumentAfter returns the name of the positional argument of parent
+// that follows sch. Arguments are bound in the order they are declared,
+// so once sch has taken its value the next argument is expected. If sch
+// is not itself an argument of parent, parent's first argument is
+// returned.
+func argumentAfter(parent, sch schema.Node) string {
+	args := parent.Arguments()
+	if _, ok := sch.(schema.OpdArgument); ok {
+		for i, a := range args {
+			if a != sch.Name() {
+				continue
+			}
+			if i+1 < len(args) {
+				return args[i+1]
+			}
+			return ""
+		}
+	}
+	return firstArgument(parent)
+}
+
+// nextArgument returns the argument node that a value following sch
+// binds to, or nil if sch does not accept a value.
+func nextArgument(sch schema.Node) schema.Node {
+	parent, name := sch, firstArgument(sch)
+	if isLeafArgument(sch) {
+		parent = sch.Parent()
+		name = argumentAfter(parent, sch)
+	}
+	if name == "" {
+		return nil
+	}
+	if arg, ok := parent.Child(name).(schema.OpdArgument); ok {
+		return arg
+	}
+	return nil
+}
+
 func NewYang() *Yang {
 
-	ycfg := yangconfig.NewConfig().IncludeYangDirs("/usr/share/configd/yang").
+	const yangDir = "/usr/share/configd/yang"
+	ycfg := yangconfig.NewConfig().IncludeYangDirs(yangDir).
 		IncludeFeatures("/config/features").SystemConfig()
 
+	y, err := compileYang(ycfg, yangDir)
+	if err != nil {
+		return &Yang{}
+	}
+	return y
+}
+
+// NewYangDirs compiles the opd YANG in yangDir with the features enabled
+// in featuresDir, rather than those of the running system, as when
+// describing the commands of another system
+func NewYangDirs(yangDir, featuresDir string) (*Yang, error) {
+	return compileYang(yangconfig.NewConfig().IncludeYangDirs(yangDir).
+		IncludeFeatures(featuresDir), yangDir)
+}
+
+func compileYang(ycfg *yangconfig.Config, yangDir string) (*Yang, error) {
 	stOpd, err := schema.CompileDir(
 		&compile.Config{
 			YangLocations: ycfg.YangLocator(),
@@ -58,11 +126,10 @@ func NewYang() *Yang {
 			Filter:        compile.IsOpd},
 		nil,
 	)
-
 	if err != nil {
-		return &Yang{}
+		return nil, err
 	}
-	return &Yang{stOpd: stOpd}
+	return &Yang{stOpd: stOpd, features: dirModules(yangDir)}, nil
 }
 
 func NewTestYang(st schema.ModelSet) *Yang {
@@ -70,24 +137,97 @@ func NewTestYang(st schema.ModelSet) *Yang {
 }
 
 func (y *Yang) Completion(path []string, auth Authoriser) (map[string]string, error) {
+	return y.CompletionContext(context.Background(), path, auth.adapt())
+}
+
+// CompletionContext returns the possible completions following path
+// and their help text, omitting any a does not permit for the user
+// carried by ctx. Aliases carried by ctx are completed at the start of
+// a path, and rewritten when path starts with one. Hidden commands are
+// omitted unless ctx is from tmpl.WithHidden.
+func (y *Yang) CompletionContext(
+	ctx context.Context,
+	path []string,
+	a auth.Authoriser,
+) (map[string]string, error) {
+	az := newAuthoriser(ctx, auth.Complete, a)
+	rpath, err := y.rewrite(path, az)
+	if err != nil {
+		if _, ok := err.(*alias.MissingParamError); ok {
+			return map[string]string{}, nil
+		}
+		return nil, err
+	}
+	m, err := y.completion(rpath, az)
+	if err != nil || len(path) != 0 {
+		return m, err
+	}
+	for name, help := range alias.FromContext(ctx).Completions() {
+		if _, ok := m[name]; !ok {
+			if m == nil {
+				m = make(map[string]string)
+			}
+			m[name] = help
+		}
+	}
+	return m, nil
+}
+
+func (y *Yang) completion(path []string, az *authoriser) (map[string]string, error) {
 	if y.stOpd == nil {
 		return nil, nil
 	}
-	sn := schema.Descendant(y.stOpd, path)
-	if sn == nil {
-		return nil, nil
+	var m map[string]string
+	if tmpl := y.opdDescendant(path); tmpl != nil && tmpl.Val &&
+		isLeafArgument(tmpl.Node) &&
+		argumentAfter(tmpl.Node.Parent(), tmpl.Node) != "" {
+		m = positionalHelpMap(tmpl.Node)
+		az.removeHidden(tmpl.Node.Parent(), m)
+	} else {
+		sn := schema.Descendant(y.stOpd, path)
+		if sn == nil {
+			return nil, nil
+		}
+		m = sn.HelpMap()
+		az.removeHidden(sn, m)
 	}
-	m := sn.HelpMap()
 
+	names := make([]string, 0, len(m))
+	for k := range m {
+		names = append(names, k)
+	}
+	permits := az.permitChildren(path, names)
 	for k := range m {
-		if !permitted(authorise(path, k, auth)) {
+		if !permits[k] {
 			delete(m, k)
 		}
 	}
+	if az.err != nil {
+		return nil, az.err
+	}
 
 	return m, nil
 }
 
+// positionalHelpMap returns the completions following a value bound to
+// arg, when arg's parent declares further positional arguments. Only
+// the argument expected next is offered, alongside the parent's
+// keywords.
+func positionalHelpMap(arg schema.Node) map[string]string {
+	parent := arg.Parent()
+	next := argumentAfter(parent, arg)
+	m := make(map[string]string)
+	for _, c := range parent.OpdChildren() {
+		switch {
+		case c.Name() == next:
+			m["<"+c.Name()+">"] = schema.GetHelp(c)
+		case !isElemOf(parent.Arguments(), c.Name()):
+			m[c.Name()] = schema.GetHelp(c)
+		}
+	}
+	return m
+}
+
 type Match interface {
 	Name() string
 	Help() string
@@ -154,13 +294,18 @@ func MergeMatches(high, low [][]Match) [][]Match {
 	return mrg
 }
 
+// ProcessMatches builds the expanded path from the matches for each
+// element of path. Secret values, as reported by SecretMatch, are
+// masked in any error returned.
 func ProcessMatches(path []string, matches [][]Match) ([]string, error) {
 	var epath = make([]string, 0)
 	for idx, entry := range matches {
 		switch len(entry) {
 		case 0:
 			// No possible completions found, Invalid Command
-			return nil, &patherr.CommandInval{Path: epath, Fail: path[idx]}
+			return nil, &patherr.CommandInval{
+				Path: maskPath(epath, matchPathAttrs(matches, len(epath))),
+				Fail: path[idx]}
 
 		case 1:
 			if entry[0].IsArg() {
@@ -170,6 +315,7 @@ func ProcessMatches(path []string, matches [][]Match) ([]string, error) {
 			}
 		default:
 			// Ambiguous command, more than one possible match
+			errPath := maskPath(epath, matchPathAttrs(matches, len(epath)))
 			matches := make(map[string]string)
 			exactMatch := false
 			for _, ent := range entry {
@@ -183,7 +329,7 @@ func ProcessMatches(path []string, matches [][]Match) ([]string, error) {
 			}
 			if !exactMatch {
 				err := &patherr.PathAmbig{
-					Path:        epath,
+					Path:        errPath,
 					Fail:        path[idx],
 					Matches:     matches,
 					Operational: true}
@@ -196,16 +342,48 @@ func ProcessMatches(path []string, matches [][]Match) ([]string, error) {
 	return epath, nil
 }
 
+// ExpandMatches returns, for each element of path, the nodes it may
+// match. Elements are matched by prefix; ExpandMatchesContext matches
+// using any matching.Policy carried by its context.
 func (y *Yang) ExpandMatches(path []string, auth Authoriser) [][]Match {
+	_, matches, _ := y.ExpandMatchesContext(context.Background(), path, auth.adapt())
+	return matches
+}
+
+// ExpandMatchesContext returns the nodes each element of path may
+// match, considering only those a permits for the user carried by ctx.
+// Path is first rewritten as by ExpandContext: any alias carried by ctx
+// is replaced and elements are matched, and split, according to the
+// matching.Policy carried by ctx, if any. The rewritten path is
+// returned, each of its elements corresponding to an entry of the
+// matches. An error is returned if the rewrite or the authoriser
+// failed.
+func (y *Yang) ExpandMatchesContext(
+	ctx context.Context,
+	path []string,
+	a auth.Authoriser,
+) ([]string, [][]Match, error) {
+	az := newAuthoriser(ctx, auth.Expand, a)
+	path, err := y.rewrite(path, az)
+	if err != nil {
+		return nil, nil, err
+	}
+	matches := y.expandMatches(path, az)
+	return path, matches, az.err
+}
+
+func (y *Yang) expandMatches(path []string, az *authoriser) [][]Match {
 	type results struct {
 		m     [][]Match
 		cpath []string
+		used  usedOptions
 	}
 	eMatches := make([][]Match, 0, len(path))
 	if y.stOpd == nil {
 		return eMatches
 	}
 	cpath := make([]string, 0, len(path))
+	policy := matching.FromContext(az.ctx)
 
 	rslts := &results{m: eMatches, cpath: cpath}
 	var ( //predeclare recursive functions
@@ -219,11 +397,11 @@ func (y *Yang) ExpandMatches(path []string, auth Authoriser) [][]Match {
 		if len(path) < 1 {
 			return r
 		}
-		// Check to see if the OpcCommand has an argument, if so
+		// Check to see if the OpcCommand has arguments, if so
 		// if current value does not match a child node, it must be
-		// the argument value
+		// an argument value
 		for _, ch := range sch.Children() {
-			if ch.Name() == sch.Arguments()[0] {
+			if isElemOf(sch.Arguments(), ch.Name()) {
 				continue
 			}
 			if path[0] == ch.Name() {
@@ -268,34 +446,52 @@ func (y *Yang) ExpandMatches(path []string, auth Authoriser) [][]Match {
 		val, path := path[0], path[1:]
 
 		children := sch.Children()
-		var argNm = ""
-		if len(children) == 0 && sch.Parent() != nil {
-			children = sch.Parent().Children()
-			if len(sch.Parent().Arguments()) > 0 {
-				argNm = sch.Parent().Arguments()[0]
-			}
-		} else if len(sch.Arguments()) > 0 {
-			argNm = sch.Arguments()[0]
+		argNm := firstArgument(sch)
+		if p := sch.Parent(); p != nil &&
+			(len(children) == 0 || isLeafArgument(sch)) {
+			children = p.Children()
+			argNm = argumentAfter(p, sch)
 		}
-		var argChild schema.Node
-		var nextNode schema.Node
+		candidates := make([]schema.Node, 0, len(children))
 		for _, c := range children {
+			candidates = append(candidates, c.(schema.Node))
+		}
+		// Options of the enclosing command may be given in any order
+		candidates = appendScopeOptions(candidates, sch)
+
+		var argChild schema.Node
+		available := make(map[string]schema.Node, len(candidates))
+		var avNames []string
+		for _, c := range candidates {
 			name := c.Name()
 			if name == argNm {
-				argChild = c.(schema.Node)
-			} else if name == val {
+				argChild = c
+			} else if isHidden(c) && !policy.Exact(name, val) {
+				// Hidden nodes are only matched by their full name
+				continue
+			} else if r.used.available(c) {
+				available[name] = c
+				avNames = append(avNames, name)
+			}
+		}
+		names := policy.Filter(val, avNames)
+
+		// Authorise all candidates in one go
+		permits := az.permitChildren(r.cpath, names)
+		var nextNode schema.Node
+		for _, name := range names {
+			c := available[name]
+			if !permits[name] {
+				continue
+			}
+			if policy.Exact(name, val) {
 				//exact matches are never ambiguous make a single match slice
-				if permitted(authorise(r.cpath, c.Name(), auth)) {
-					matches = []Match{expandMatch{node: c.(schema.Node)}}
-					nextNode = c.(schema.Node)
-					break
-				}
-			} else if strings.HasPrefix(name, val) {
-				if permitted(authorise(r.cpath, c.Name(), auth)) {
-					matches = append(matches, expandMatch{node: c.(schema.Node)})
-					nextNode = c.(schema.Node)
-				}
+				matches = []Match{expandMatch{node: c}}
+				nextNode = c
+				break
 			}
+			matches = append(matches, expandMatch{node: c})
+			nextNode = c
 		}
 
 		switch len(matches) {
@@ -314,6 +510,7 @@ func (y *Yang) ExpandMatches(path []string, auth Authoriser) [][]Match {
 		case 1:
 			r.m = append(r.m, matches)
 			r.cpath = append(r.cpath, matches[0].Name())
+			r.used.use(nextNode)
 			return processnode(nextNode, path, r)
 		default:
 			r.m = append(r.m, matches)
@@ -325,8 +522,215 @@ func (y *Yang) ExpandMatches(path []string, auth Authoriser) [][]Match {
 
 }
 
+// Expand expands each element of path to the full name of the node it
+// matches. If the expanded path cannot be run as it stands, it is
+// returned along with a *CommandIncomplete error listing the authorised
+// continuations. ExpandContext also rewrites aliases carried by its
+// context, returning the expansion of the rewritten path.
+//
+// Note that a non-nil error no longer implies a nil path: callers which
+// only need the expansion, such as for completion, should accept a
+// *CommandIncomplete, while those about to run the command should
+// treat it as they would any other error.
 func (y *Yang) Expand(path []string, auth Authoriser) ([]string, error) {
-	return ProcessMatches(path, y.ExpandMatches(path, auth))
+	return y.ExpandContext(context.Background(), path, auth.adapt())
+}
+
+// ExpandContext is Expand for the user carried by ctx. Failure of the
+// authoriser is returned as an *auth.Error rather than treated as the
+// path being denied.
+func (y *Yang) ExpandContext(
+	ctx context.Context,
+	path []string,
+	a auth.Authoriser,
+) ([]string, error) {
+	epath, _, err := y.expand(path, newAuthoriser(ctx, auth.Expand, a))
+	return epath, err
+}
+
+// ExpandWithSuggestions is ExpandContext, except that where an element
+// of path matches nothing the *patherr.CommandInval returned is wrapped
+// in a *CommandInvalid suggesting the closest authorised candidates.
+func (y *Yang) ExpandWithSuggestions(
+	ctx context.Context,
+	path []string,
+	a auth.Authoriser,
+) ([]string, error) {
+	az := newAuthoriser(ctx, auth.Expand, a)
+	path, err := y.rewrite(path, az)
+	if err != nil {
+		return nil, err
+	}
+	epath, matches, err := y.expandPath(path, az)
+	if inval, ok := err.(*patherr.CommandInval); ok {
+		return nil, y.suggest(path, matches, inval, az)
+	}
+	return epath, err
+}
+
+func (y *Yang) expand(path []string, az *authoriser) ([]string, [][]Match, error) {
+	path, err := y.rewrite(path, az)
+	if err != nil {
+		return nil, nil, err
+	}
+	return y.expandPath(path, az)
+}
+
+// expandPath is expand for a path which has been rewritten
+func (y *Yang) expandPath(path []string, az *authoriser) ([]string, [][]Match, error) {
+	matches := y.expandMatches(path, az)
+	if az.err != nil {
+		return nil, matches, az.err
+	}
+	epath, err := ProcessMatches(path, matches)
+	if err != nil || len(epath) == 0 {
+		return epath, matches, err
+	}
+
+	if sch, val := lastMatchNode(matches, epath[len(epath)-1]); sch != nil &&
+		isIncomplete(sch, val) {
+		comps, err := y.completion(epath, az.withOp(auth.Complete))
+		if err != nil {
+			return nil, matches, err
+		}
+		return epath, matches, &CommandIncomplete{
+			Path:    epath,
+			Attrs:   matchPathAttrs(matches, len(epath)),
+			Matches: comps,
+		}
+	}
+	return epath, matches, nil
+}
+
+// rewrite rewrites any alias at the start of path, then splits elements
+// spanning several commands under the request's matching policy.
+func (y *Yang) rewrite(path []string, az *authoriser) ([]string, error) {
+	path, err := y.rewriteAliases(path, az)
+	if err != nil || y.stOpd == nil {
+		return path, err
+	}
+	return matching.FromContext(az.ctx).Split(path, y.splitChildren(az)), nil
+}
+
+// splitChildren returns the visible commands and options az permits
+// following an expanded path, and whether a value may be given there
+// instead.
+func (y *Yang) splitChildren(az *authoriser) matching.Children {
+	return func(ep []string) ([]string, bool) {
+		t := y.opdDescendant(ep)
+		if t == nil {
+			return nil, false
+		}
+		sch := t.Node
+		if _, ok := sch.(schema.OpdOption); ok && !t.Val {
+			if _, ok := sch.Type().(schema.Empty); !ok {
+				// Option awaiting its value
+				return nil, true
+			}
+		}
+		parent, argNm := sch, firstArgument(sch)
+		if p := sch.Parent(); p != nil &&
+			(len(sch.Children()) == 0 || isLeafArgument(sch)) {
+			parent, argNm = p, argumentAfter(p, sch)
+		}
+		var names []string
+		for _, c := range appendScopeOptions(parent.OpdChildren(), sch) {
+			if isElemOf(parent.Arguments(), c.Name()) || isHidden(c) {
+				continue
+			}
+			names = append(names, c.Name())
+		}
+		permits := az.permitChildren(ep, names)
+		permitted := names[:0]
+		for _, name := range names {
+			if permits[name] {
+				permitted = append(permitted, name)
+			}
+		}
+		return permitted, argNm != ""
+	}
+}
+
+// rewriteAliases replaces any alias carried by the request's context
+// at the start of path with its expansion. The alias must not shadow a
+// top level command, whether of y or of the other sources whose
+// commands the context carries. The expanded path is then authorised
+// as any other.
+func (y *Yang) rewriteAliases(path []string, az *authoriser) ([]string, error) {
+	aliases := alias.FromContext(az.ctx)
+	if aliases == nil || y.stOpd == nil {
+		return path, nil
+	}
+	var commands []string
+	for _, c := range y.stOpd.Children() {
+		commands = append(commands, c.Name())
+	}
+	return aliases.Rewrite(path, append(commands, alias.Commands(az.ctx)...))
+}
+
+// suggest adds to inval the authorised candidates closest to the
+// element of path which failed to match
+func (y *Yang) suggest(
+	path []string,
+	matches [][]Match,
+	inval *patherr.CommandInval,
+	az *authoriser,
+) error {
+	idx := len(inval.Path)
+	prefix, err := ProcessMatches(path[:idx], matches[:idx])
+	if err != nil {
+		return inval
+	}
+	comps, err := y.completion(prefix, az.withOp(auth.Complete))
+	if err != nil {
+		return err
+	}
+	var candidates []string
+	for name := range comps {
+		if !strings.HasPrefix(name, "<") {
+			candidates = append(candidates, name)
+		}
+	}
+	return WithSuggestions(inval, candidates)
+}
+
+// lastMatchNode returns the schema node, if any, that the final
+// element of an expanded path was matched against and whether it was
+// matched as a value.
+func lastMatchNode(matches [][]Match, last string) (schema.Node, bool) {
+	if len(matches) == 0 {
+		return nil, false
+	}
+	for _, m := range matches[len(matches)-1] {
+		if em, ok := m.(expandMatch); ok && (em.isarg || em.Name() == last) {
+			return em.node, em.isarg
+		}
+	}
+	return nil, false
+}
+
+// isIncomplete reports whether sch, reached as the last element of a
+// path, must be followed by further elements before it can be run.
+func isIncomplete(sch schema.Node, val bool) bool {
+	var onEnter string
+	switch v := sch.(type) {
+	case schema.OpdOption:
+		if _, ok := v.Type().(schema.Empty); !ok && !val {
+			// Option is missing its value
+			return true
+		}
+		onEnter = v.OnEnter()
+	case schema.OpdCommand:
+		onEnter = v.OnEnter()
+	case schema.OpdArgument:
+		onEnter = v.OnEnter()
+	default:
+		return false
+	}
+	if onEnter != "" {
+		return false
+	}
+	return len(sch.OpdChildren()) > 0 || nextArgument(sch) != nil
 }
 
 func (y *Yang) validatePath(ps []string) error {
@@ -338,7 +742,10 @@ func (y *Yang) validatePath(ps []string) error {
 	for i, v := range ps {
 		sn = sn.SchemaChild(v)
 		if sn == nil {
-			return &patherr.PathInval{Path: ps[:i], Fail: v}
+			return &patherr.PathInval{
+				Path: maskPath(ps[:i], y.PathAttrs(ps[:i])),
+				Fail: v,
+			}
 		}
 	}
 	return nil
@@ -357,12 +764,125 @@ func (y *Yang) opdSchemaPathDescendant(ps []string) (*schema.TmplCompat, error)
 	}
 	tmpl := y.stOpd.OpdPathDescendant(ps)
 	if tmpl == nil {
-		return nil, y.getPathError(ps, "Schema not found")
+		// Paths binding values to more than one positional
+		// argument of a node are resolved locally
+		if tmpl = y.opdDescendant(ps); tmpl == nil {
+			return nil, y.getPathError(ps, "Schema not found")
+		}
 	}
 	return tmpl, nil
 }
 
+// opdStep binds v, the path element following sch, to either a
+// keyword child of sch or to the positional argument expected next.
+// The returned bool is true if v was bound as an argument value.
+func opdStep(sch schema.Node, v string) (schema.Node, bool) {
+	parent, argNm := sch, firstArgument(sch)
+	if p := sch.Parent(); p != nil &&
+		(len(sch.Children()) == 0 || isLeafArgument(sch)) {
+		parent, argNm = p, argumentAfter(p, sch)
+	}
+
+	for _, c := range appendScopeOptions(parent.OpdChildren(), sch) {
+		if c.Name() == v && !isElemOf(parent.Arguments(), v) {
+			return c, false
+		}
+	}
+	if argNm == "" {
+		return nil, false
+	}
+	if arg, ok := parent.Child(argNm).(schema.OpdArgument); ok {
+		return arg, true
+	}
+	return nil, false
+}
+
+// opdWalk resolves each element of ps in turn, calling fn with the
+// element's index and the node it was bound to. val is true where the
+// element is a value of an option or argument rather than a keyword.
+// As when expanding, an option may only be given once unless marked
+// repeatable. Secret values are masked in the errors returned.
+func (y *Yang) opdWalk(
+	ps []string,
+	fn func(i int, sch schema.Node, val bool) error,
+) (*schema.TmplCompat, error) {
+	var sch schema.Node = y.stOpd
+	var used usedOptions
+	attrs := pathutil.NewPathAttrs()
+	val := false
+	for i, v := range ps {
+		if _, ok := sch.(schema.OpdOption); ok && !val {
+			if _, ok := sch.Type().(schema.Empty); !ok {
+				// Option's value, remain on the option node
+				val = true
+				attrs.Attrs = append(attrs.Attrs, valueAttr(sch, val))
+				if err := fn(i, sch, val); err != nil {
+					return nil, err
+				}
+				continue
+			}
+		}
+		sch, val = opdStep(sch, v)
+		if sch == nil || (!val && !used.available(sch)) {
+			return nil, &patherr.PathInval{
+				Path: maskPath(ps[:i], &attrs),
+				Fail: v,
+			}
+		}
+		if !val {
+			used.use(sch)
+		}
+		attrs.Attrs = append(attrs.Attrs, valueAttr(sch, val))
+		if err := fn(i, sch, val); err != nil {
+			return nil, err
+		}
+	}
+	return &schema.TmplCompat{Node: sch, Val: val}, nil
+}
+
+func noWalk(int, schema.Node, bool) error { return nil }
+
+// valueAttr returns the attributes of an element bound to sch, which
+// is secret if it is the value of a secret option or argument
+func valueAttr(sch schema.Node, val bool) pathutil.PathElementAttrs {
+	attr := pathutil.NewPathElementAttrs()
+	attr.Secret = val && isSecret(sch)
+	return attr
+}
+
+func (y *Yang) opdDescendant(ps []string) *schema.TmplCompat {
+	tmpl, err := y.opdWalk(ps, noWalk)
+	if err != nil {
+		return nil
+	}
+	return tmpl
+}
+
+// validateValues checks every option and argument value in path
+// against the node it is bound to.
+func (y *Yang) validateValues(vctx schema.ValidateCtx, path []string) error {
+	_, err := y.opdWalk(path, func(i int, sch schema.Node, val bool) error {
+		if !val {
+			return nil
+		}
+		return sch.Validate(vctx, path[:i], path[i:i+1])
+	})
+	return err
+}
+
 func (y *Yang) TmplGetChildren(path []string, auth Authoriser) ([]string, error) {
+	return y.TmplGetChildrenContext(context.Background(), path, auth.adapt())
+}
+
+// TmplGetChildrenContext returns the names of the children of path
+// which a permits for the user carried by ctx. Hidden children are
+// omitted unless ctx is from tmpl.WithHidden.
+func (y *Yang) TmplGetChildrenContext(
+	ctx context.Context,
+	path []string,
+	a auth.Authoriser,
+) ([]string, error) {
+	az := newAuthoriser(ctx, auth.Complete, a)
 	if y.stOpd == nil {
 		return nil, nil
 	}
@@ -371,36 +891,38 @@ func (y *Yang) TmplGetChildren(path []string, auth Authoriser) ([]string, error)
 		return nil, err
 	}
 
-	argName := ""
+	var argNames []string
 	switch v := tmpl.Node.(type) {
 	case schema.OpdCommand:
-		if len(v.Arguments()) > 0 {
-			argName = v.Arguments()[0]
-		}
+		argNames = v.Arguments()
 	case schema.OpdOption:
 		if !tmpl.Val {
 			return make([]string, 0), nil
 		}
-		if len(v.Arguments()) > 0 {
-			argName = v.Arguments()[0]
-		}
+		argNames = v.Arguments()
 	case schema.OpdArgument:
-		if len(v.Arguments()) > 0 {
-			argName = v.Arguments()[0]
-		}
+		argNames = v.Arguments()
 	}
 
 	chs := tmpl.Node.OpdChildren()
 
-	strs := make([]string, 0, len(chs))
+	names := make([]string, 0, len(chs))
 	for _, n := range chs {
-		if !permitted(authorise(path, n.Name(), auth)) {
-			continue
+		if !isElemOf(argNames, n.Name()) && !az.hidden(n) {
+			names = append(names, n.Name())
 		}
-		if n.Name() != argName {
-			strs = append(strs, n.Name())
+	}
+	permits := az.permitChildren(path, names)
+
+	strs := make([]string, 0, len(names))
+	for _, name := range names {
+		if permits[name] {
+			strs = append(strs, name)
 		}
 	}
+	if az.err != nil {
+		return nil, az.err
+	}
 	return strs, nil
 
 }
@@ -479,14 +1001,8 @@ func (y *Yang) TmplGet(path []string) (*tmpl.OpTmpl, error) {
 		secret = v.Secret()
 		passOpcArgs = v.PassOpcArgs()
 	}
-	switch ty.(type) {
-	case schema.Empty:
-	case schema.Integer, schema.Uinteger:
-		m["type"] = "u32"
-	case schema.Boolean:
-		m["type"] = "bool"
-	default:
-		m["type"] = "txt"
+	if t := typeName(ty); t != "" {
+		m["type"] = t
 	}
 
 	template := tmpl.NewOpTmpl(m["allowed"], m["help"], "", m["run"])
@@ -498,12 +1014,28 @@ func (y *Yang) TmplGet(path []string) (*tmpl.OpTmpl, error) {
 		template.SetSecret(secret)
 	}
 	template.SetPassOpcArgs(passOpcArgs)
+	setLimits(template, sn)
+	setConfirm(template, sn)
+	setDeprecated(template, sn)
 
 	template.SetYang(true)
 
 	return template, nil
 }
 
+// typeName returns the template type name corresponding to ty
+func typeName(ty schema.Type) string {
+	switch ty.(type) {
+	case schema.Empty:
+		return ""
+	case schema.Integer, schema.Uinteger:
+		return "u32"
+	case schema.Boolean:
+		return "bool"
+	}
+	return "txt"
+}
+
 func (y *Yang) TmplGetAllowed(path []string) (string, error) {
 	if y.stOpd == nil {
 		return "", nil
@@ -540,14 +1072,13 @@ func (y *Yang) TmplGetAllowed(path []string) (string, error) {
 			}
 		}
 	case schema.OpdArgument:
-		// Arguments only show allowed for any child arguments.
+		// Arguments only show allowed for any child arguments, or for
+		// the argument following this one in their parent.
 		// The parents node should have shown this arguments allowedllowed
 		allowed = ""
-		if len(v.Arguments()) > 0 {
-			if arg, ok := tmpl.Node.Child(v.Arguments()[0]).(schema.OpdArgument); ok {
-				allowed = arg.ConfigdExt().OpdAllowed
-				argNode = true
-			}
+		if arg := nextArgument(v); arg != nil {
+			allowed = arg.ConfigdExt().OpdAllowed
+			argNode = true
 		}
 
 	}
@@ -567,7 +1098,20 @@ func (y *Yang) TmplValidateValues(path []string) (bool, error) {
 		CurPath: path,
 	}
 
-	err := y.stOpd.Validate(vctx, []string{}, path)
+	var err error
+	switch {
+	case y.stOpd.OpdPathDescendant(path) != nil:
+		// The schema does not know which options may be repeated
+		if _, err = y.opdWalk(path, noWalk); err == nil {
+			err = y.stOpd.Validate(vctx, []string{}, path)
+		}
+	case y.opdDescendant(path) != nil:
+		// Values for more than one positional argument of a node
+		// are validated individually against the argument they bind to
+		err = y.validateValues(vctx, path)
+	default:
+		err = y.stOpd.Validate(vctx, []string{}, path)
+	}
 	return err == nil, formatError(err)
 }
 